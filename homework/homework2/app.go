@@ -0,0 +1,215 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownCallback 退出的时候执行的回调，例如刷新缓存、关闭数据库连接
+// 需要在 ctx 超时之前返回
+type ShutdownCallback func(ctx context.Context)
+
+// App 管理多个 HTTPServer 的生命周期，例如业务端口和管理端口
+//
+//	app := web.NewApp(web.AppWithShutdownCallbacks(func(ctx context.Context) {
+//		db.Close()
+//	}))
+//	app.AddServer("business", ":8080", business)
+//	app.AddServer("admin", ":8081", admin)
+//	err := app.Start()
+//
+// 收到退出信号之后，App 会：
+// 1. 停止接收新的请求
+// 2. 等待已有的请求执行完毕，最多等待 shutdownTimeout
+// 3. 并发执行所有的 ShutdownCallback，每个最多执行 cbTimeout
+// 4. Start 返回，由调用者决定怎么退出
+// 在这个过程中再次收到退出信号的话，会直接退出进程
+type App struct {
+	servers []*appServer
+
+	// shutdownTimeout 等待请求执行完毕的时间
+	shutdownTimeout time.Duration
+	// cbTimeout 每一个回调的超时时间
+	cbTimeout time.Duration
+	cbs       []ShutdownCallback
+	signals   []os.Signal
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type appServer struct {
+	name string
+	srv  *http.Server
+	s    *HTTPServer
+}
+
+type AppOption func(app *App)
+
+func NewApp(opts ...AppOption) *App {
+	res := &App{
+		shutdownTimeout: 30 * time.Second,
+		cbTimeout:       3 * time.Second,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		stop:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// AppWithShutdownTimeout 等待请求执行完毕的最长时间，超过之后直接关闭连接
+func AppWithShutdownTimeout(timeout time.Duration) AppOption {
+	return func(app *App) {
+		app.shutdownTimeout = timeout
+	}
+}
+
+// AppWithShutdownCallbacks 注册退出的时候执行的回调
+func AppWithShutdownCallbacks(cbs ...ShutdownCallback) AppOption {
+	return func(app *App) {
+		app.cbs = append(app.cbs, cbs...)
+	}
+}
+
+// AppWithCallbackTimeout 每个回调的超时时间，默认是 3 秒
+func AppWithCallbackTimeout(timeout time.Duration) AppOption {
+	return func(app *App) {
+		app.cbTimeout = timeout
+	}
+}
+
+// AppWithSignals 监听的退出信号，默认是 SIGINT 和 SIGTERM
+func AppWithSignals(signals ...os.Signal) AppOption {
+	return func(app *App) {
+		app.signals = signals
+	}
+}
+
+// AddServer 添加一个 HTTPServer，name 只用于输出日志
+func (app *App) AddServer(name string, addr string, s *HTTPServer) *App {
+	srv := &http.Server{Addr: addr, Handler: s}
+	// Shutdown 不会处理被接管的连接，所以需要主动关闭 WebSocket 连接
+	srv.RegisterOnShutdown(s.wsConns.closeAll)
+	app.servers = append(app.servers, &appServer{
+		name: name,
+		srv:  srv,
+		s:    s,
+	})
+	return app
+}
+
+// Start 启动所有的 HTTPServer，并且阻塞到退出完成
+// 有 HTTPServer 启动失败或者异常退出的时候，也会执行退出流程，并且返回这个错误
+func (app *App) Start() error {
+	lns := make([]net.Listener, 0, len(app.servers))
+	for _, as := range app.servers {
+		ln, err := net.Listen("tcp", as.srv.Addr)
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return fmt.Errorf("web: 服务器 %s 监听 %s 失败 %w", as.name, as.srv.Addr, err)
+		}
+		lns = append(lns, ln)
+	}
+
+	// 在开始处理请求之前监听信号，避免漏掉
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, app.signals...)
+	defer signal.Stop(ch)
+
+	errCh := make(chan error, len(app.servers))
+	for i, as := range app.servers {
+		as.s.Freeze()
+		ln := lns[i]
+		go func(as *appServer) {
+			log.Printf("web: 服务器 %s 启动，监听 %s", as.name, ln.Addr())
+			if err := as.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("web: 服务器 %s 异常退出 %w", as.name, err)
+			}
+		}(as)
+	}
+
+	var err error
+	select {
+	case sig := <-ch:
+		log.Printf("web: 收到信号 %s，开始退出", sig)
+	case <-app.stop:
+		log.Println("web: 开始退出")
+	case err = <-errCh:
+		log.Println(err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case sig := <-ch:
+			// 再次收到信号，说明用户不想等了
+			log.Printf("web: 再次收到信号 %s，强制退出", sig)
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+	app.shutdown()
+	return err
+}
+
+// Stop 触发退出流程，和收到退出信号的效果一样
+// Stop 立刻返回，需要等待 Start 返回才代表退出完成
+func (app *App) Stop() {
+	app.stopOnce.Do(func() {
+		close(app.stop)
+	})
+}
+
+func (app *App) shutdown() {
+	// 所有服务器共享一个超时时间
+	ctx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, as := range app.servers {
+		wg.Add(1)
+		go func(as *appServer) {
+			defer wg.Done()
+			// Shutdown 会关闭监听，然后等待已有的请求执行完毕
+			if err := as.srv.Shutdown(ctx); err != nil {
+				log.Printf("web: 服务器 %s 没有正常关闭 %v", as.name, err)
+				_ = as.srv.Close()
+			}
+		}(as)
+	}
+	wg.Wait()
+
+	for _, cb := range app.cbs {
+		wg.Add(1)
+		go func(cb ShutdownCallback) {
+			defer wg.Done()
+			cbCtx, cancel := context.WithTimeout(context.Background(), app.cbTimeout)
+			defer cancel()
+			cbDone := make(chan struct{})
+			go func() {
+				cb(cbCtx)
+				close(cbDone)
+			}()
+			// 回调没有遵守超时时间的话，就不再等它了
+			select {
+			case <-cbDone:
+			case <-cbCtx.Done():
+				log.Println("web: 回调超时", cbCtx.Err())
+			}
+		}(cb)
+	}
+	wg.Wait()
+	log.Println("web: 退出完成")
+}
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestApp_Stop(t *testing.T) {
+	business := NewHTTPServer()
+	started := make(chan struct{})
+	business.Get("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.RespData = []byte("slow done")
+	})
+	admin := NewHTTPServer()
+	admin.Get("/health", func(ctx *Context) {
+		ctx.RespData = []byte("ok")
+	})
+
+	var cbCnt int32
+	var slowDone int32
+	app := NewApp(AppWithShutdownCallbacks(
+		func(ctx context.Context) {
+			// 回调在请求执行完毕之后才执行
+			assert.Equal(t, int32(1), atomic.LoadInt32(&slowDone))
+			atomic.AddInt32(&cbCnt, 1)
+		},
+		func(ctx context.Context) {
+			atomic.AddInt32(&cbCnt, 1)
+		},
+		func(ctx context.Context) {
+			// 不遵守超时时间的回调不会阻塞退出
+			time.Sleep(time.Hour)
+		},
+	), AppWithCallbackTimeout(100*time.Millisecond))
+	businessAddr, adminAddr := freeAddr(t), freeAddr(t)
+	app.AddServer("business", businessAddr, business).AddServer("admin", adminAddr, admin)
+
+	appErr := make(chan error, 1)
+	go func() {
+		appErr <- app.Start()
+	}()
+	assert.Equal(t, "ok", waitGet(t, "http://"+adminAddr+"/health"))
+
+	slowResp := make(chan string, 1)
+	go func() {
+		resp := waitGet(t, "http://"+businessAddr+"/slow")
+		atomic.StoreInt32(&slowDone, 1)
+		slowResp <- resp
+	}()
+	<-started
+	app.Stop()
+
+	select {
+	case err := <-appErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("App 没有退出")
+	}
+	// 已经在执行的请求正常返回
+	assert.Equal(t, "slow done", <-slowResp)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cbCnt))
+
+	// 不再接收新的请求
+	_, err := http.Get("http://" + adminAddr + "/health")
+	assert.Error(t, err)
+}
+
+func TestApp_Signal(t *testing.T) {
+	s := NewHTTPServer()
+	s.Get("/block", func(ctx *Context) {
+		<-ctx.Req.Context().Done()
+	})
+	s.Get("/health", func(ctx *Context) {
+		ctx.RespData = []byte("ok")
+	})
+	var called int32
+	app := NewApp(AppWithShutdownTimeout(100*time.Millisecond),
+		AppWithSignals(syscall.SIGUSR1),
+		AppWithShutdownCallbacks(func(ctx context.Context) {
+			atomic.StoreInt32(&called, 1)
+		}))
+	addr := freeAddr(t)
+	app.AddServer("business", addr, s)
+
+	appErr := make(chan error, 1)
+	go func() {
+		appErr <- app.Start()
+	}()
+	assert.Equal(t, "ok", waitGet(t, "http://"+addr+"/health"))
+	go func() {
+		_, _ = http.Get("http://" + addr + "/block")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case err := <-appErr:
+		// 请求一直没有结束，超时之后也会退出
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("App 没有退出")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called))
+}
+
+func TestApp_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	app := NewApp()
+	app.AddServer("ok", freeAddr(t), NewHTTPServer())
+	app.AddServer("busy", ln.Addr().String(), NewHTTPServer())
+	err = app.Start()
+	assert.ErrorContains(t, err, "web: 服务器 busy 监听 "+ln.Addr().String()+" 失败")
+}
+
+// freeAddr 找一个空闲的端口
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+// waitGet 等待服务器启动之后发送请求
+func waitGet(t *testing.T, url string) string {
+	for i := 0; i < 50; i++ {
+		resp, err := http.Get(url)
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		defer resp.Body.Close()
+		bs, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(bs)
+	}
+	t.Fatalf("请求 %s 失败", url)
+	return ""
+}
@@ -0,0 +1,249 @@
+package web
+
+import (
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 绑定用到的标签，例如：
+//
+//	type UserReq struct {
+//		ID       int64     `path:"id"`
+//		Page     int       `query:"page"`
+//		Tags     []string  `query:"tag"`
+//		Token    string    `header:"X-Token"`
+//		Name     string    `form:"name" json:"name"`
+//		Birthday time.Time `query:"birthday" time_format:"2006-01-02"`
+//	}
+//
+// 没有对应标签的字段不会被绑定，标签是 "-" 的也一样
+// 字段支持 string、bool、整数、浮点数、time.Time、time.Duration、
+// 实现了 encoding.TextUnmarshaler 的类型，以及它们的指针和切片
+const (
+	tagQuery  = "query"
+	tagForm   = "form"
+	tagPath   = "path"
+	tagHeader = "header"
+	// tagTimeFormat time.Time 的格式，默认是 time.RFC3339
+	tagTimeFormat = "time_format"
+)
+
+// Bind 绑定请求的所有部分
+// 先根据 Content-Type 解析请求体，支持 JSON、XML 和表单，
+// 然后依次绑定查询参数、请求头和路径参数，后绑定的会覆盖前面的
+func (c *Context) Bind(val any) error {
+	if err := c.bindBody(val); err != nil {
+		return err
+	}
+	if err := c.BindQuery(val); err != nil {
+		return err
+	}
+	if err := c.BindHeader(val); err != nil {
+		return err
+	}
+	return c.BindPath(val)
+}
+
+func (c *Context) bindBody(val any) error {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody || c.Req.ContentLength == 0 {
+		return nil
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("web: 非法的 Content-Type %s", contentType)
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return c.BindJSON(val)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return c.BindXML(val)
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		return c.BindForm(val)
+	default:
+		return fmt.Errorf("web: 不支持的 Content-Type %s", contentType)
+	}
+}
+
+func (c *Context) BindXML(val any) error {
+	if c.Req.Body == nil {
+		return errors.New("web: body 为 nil")
+	}
+	return xml.NewDecoder(c.Req.Body).Decode(val)
+}
+
+// BindQuery 使用查询参数绑定 query 标签的字段
+func (c *Context) BindQuery(val any) error {
+	if c.cacheQueryValues == nil {
+		c.cacheQueryValues = c.Req.URL.Query()
+	}
+	return bindValues(val, tagQuery, func(key string) []string {
+		return c.cacheQueryValues[key]
+	})
+}
+
+// BindForm 使用表单绑定 form 标签的字段，和 FormValue 一样包含查询参数
+func (c *Context) BindForm(val any) error {
+	var err error
+	if strings.HasPrefix(c.Req.Header.Get("Content-Type"), "multipart/form-data") {
+		err = c.Req.ParseMultipartForm(32 << 20)
+	} else {
+		err = c.Req.ParseForm()
+	}
+	if err != nil {
+		return err
+	}
+	return bindValues(val, tagForm, func(key string) []string {
+		return c.Req.Form[key]
+	})
+}
+
+// BindPath 使用路径参数绑定 path 标签的字段
+func (c *Context) BindPath(val any) error {
+	return bindValues(val, tagPath, func(key string) []string {
+		v, ok := c.PathParams[key]
+		if !ok {
+			return nil
+		}
+		return []string{v}
+	})
+}
+
+// BindHeader 使用请求头绑定 header 标签的字段，标签不区分大小写
+func (c *Context) BindHeader(val any) error {
+	return bindValues(val, tagHeader, func(key string) []string {
+		return c.Req.Header.Values(key)
+	})
+}
+
+// bindValues 把 get 返回的值设置到 tag 标签的字段上，没有值的字段保持不变
+func bindValues(val any, tag string, get func(key string) []string) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("web: 只支持绑定到结构体指针，实际是 %T", val)
+	}
+	return bindStruct(rv.Elem(), tag, get)
+}
+
+func bindStruct(rv reflect.Value, tag string, get func(key string) []string) error {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		key, ok := field.Tag.Lookup(tag)
+		// 组合的结构体，例如公共的分页参数
+		// 和 encoding/json 一样，即便结构体本身不是公开的，它公开的字段也会被绑定
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindStruct(rv.Field(i), tag, get); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok || !field.IsExported() || key == "-" {
+			continue
+		}
+		vals := get(key)
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setField(rv.Field(i), vals, field.Tag.Get(tagTimeFormat)); err != nil {
+			return fmt.Errorf("web: 字段 %s 绑定失败 %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textUnmarshalType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func setField(fv reflect.Value, vals []string, timeFormat string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		res := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(res.Index(i), val, timeFormat); err != nil {
+				return err
+			}
+		}
+		fv.Set(res)
+		return nil
+	}
+	return setValue(fv, vals[0], timeFormat)
+}
+
+func setValue(fv reflect.Value, val string, timeFormat string) error {
+	typ := fv.Type()
+	if typ.Kind() == reflect.Pointer {
+		ptr := reflect.New(typ.Elem())
+		if err := setValue(ptr.Elem(), val, timeFormat); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	switch typ {
+	case timeType:
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		t, err := time.ParseInLocation(timeFormat, val, time.Local)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	if reflect.PtrTo(typ).Implements(textUnmarshalType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, typ.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("不支持的类型 %s", typ)
+	}
+	return nil
+}
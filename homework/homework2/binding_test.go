@@ -0,0 +1,222 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Page int `query:"page" form:"page"`
+	Size int `query:"size" form:"size"`
+}
+
+type bindUserReq struct {
+	bindPage
+	ID       int64         `path:"id" json:"id" xml:"id"`
+	Name     string        `form:"name" json:"name" xml:"name"`
+	Tags     []string      `query:"tag" form:"tag"`
+	Scores   []int         `query:"score"`
+	Admin    bool          `query:"admin"`
+	Age      *uint8        `query:"age" json:"age"`
+	Ratio    float64       `query:"ratio"`
+	Birthday time.Time     `query:"birthday" time_format:"2006-01-02"`
+	Created  time.Time     `query:"created"`
+	Timeout  time.Duration `query:"timeout"`
+	IP       net.IP        `header:"X-Real-Ip"`
+	Token    string        `header:"x-token"`
+	Ignored  string        `query:"-"`
+	internal string        `query:"internal"`
+}
+
+func TestContext_BindQuery(t *testing.T) {
+	age := uint8(18)
+	testCases := []struct {
+		name  string
+		query string
+
+		wantVal bindUserReq
+		wantErr string
+	}{
+		{
+			name: "all",
+			query: "page=2&size=20&tag=a&tag=b&score=1&score=2&admin=true&age=18&ratio=0.5" +
+				"&birthday=2000-01-02&created=2022-11-01T10:00:00Z&timeout=3s&Ignored=x&internal=x&name=Tom",
+			wantVal: bindUserReq{
+				bindPage: bindPage{Page: 2, Size: 20},
+				Tags:     []string{"a", "b"},
+				Scores:   []int{1, 2},
+				Admin:    true,
+				Age:      &age,
+				Ratio:    0.5,
+				Birthday: time.Date(2000, 1, 2, 0, 0, 0, 0, time.Local),
+				Created:  time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC),
+				Timeout:  3 * time.Second,
+			},
+		},
+		{
+			name:    "empty",
+			query:   "",
+			wantVal: bindUserReq{},
+		},
+		{
+			name:    "invalid int",
+			query:   "page=abc",
+			wantErr: `web: 字段 Page 绑定失败 strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:    "overflow",
+			query:   "age=256",
+			wantErr: `web: 字段 Age 绑定失败 strconv.ParseUint: parsing "256": value out of range`,
+		},
+		{
+			name:    "invalid slice",
+			query:   "score=1&score=x",
+			wantErr: `web: 字段 Scores 绑定失败 strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/user?"+tc.query, nil)}
+			var val bindUserReq
+			err := ctx.BindQuery(&val)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVal.Created.Unix(), val.Created.Unix())
+			val.Created, tc.wantVal.Created = time.Time{}, time.Time{}
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+
+	ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/user", nil)}
+	var val bindUserReq
+	assert.EqualError(t, ctx.BindQuery(val), "web: 只支持绑定到结构体指针，实际是 web.bindUserReq")
+	var ptr *bindUserReq
+	assert.EqualError(t, ctx.BindQuery(ptr), "web: 只支持绑定到结构体指针，实际是 *web.bindUserReq")
+	assert.EqualError(t, ctx.BindQuery(new(int)), "web: 只支持绑定到结构体指针，实际是 *int")
+}
+
+func TestContext_BindPathAndHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/user/123", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	req.Header.Set("X-Token", "abc")
+	ctx := &Context{Req: req, PathParams: map[string]string{"id": "123"}}
+	var val bindUserReq
+	require.NoError(t, ctx.BindPath(&val))
+	require.NoError(t, ctx.BindHeader(&val))
+	assert.Equal(t, int64(123), val.ID)
+	assert.Equal(t, "10.0.0.1", val.IP.String())
+	assert.Equal(t, "abc", val.Token)
+
+	req.Header.Set("X-Real-IP", "abc")
+	assert.EqualError(t, ctx.BindHeader(&val), "web: 字段 IP 绑定失败 invalid IP address: abc")
+}
+
+func TestContext_Bind(t *testing.T) {
+	multipartBody := func() (string, *bytes.Buffer) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		_ = writer.WriteField("name", "Tom")
+		_ = writer.WriteField("tag", "a")
+		_ = writer.WriteField("tag", "b")
+		_ = writer.Close()
+		return writer.FormDataContentType(), body
+	}
+	ct, body := multipartBody()
+
+	age := uint8(18)
+	testCases := []struct {
+		name        string
+		url         string
+		contentType string
+		body        *bytes.Buffer
+
+		wantVal bindUserReq
+		wantErr string
+	}{
+		{
+			name:        "json",
+			url:         "/user/123?page=2",
+			contentType: "application/json; charset=utf-8",
+			body:        bytes.NewBufferString(`{"id": 456, "name": "Tom", "age": 18}`),
+			// 路径参数覆盖了请求体
+			wantVal: bindUserReq{bindPage: bindPage{Page: 2}, ID: 123, Name: "Tom", Age: &age},
+		},
+		{
+			name:        "xml",
+			url:         "/user/123",
+			contentType: "application/xml",
+			body:        bytes.NewBufferString(`<user><name>Tom</name></user>`),
+			wantVal:     bindUserReq{ID: 123, Name: "Tom"},
+		},
+		{
+			name:        "form",
+			url:         "/user/123?size=10",
+			contentType: "application/x-www-form-urlencoded",
+			body:        bytes.NewBufferString(`name=Tom&page=3&tag=a`),
+			wantVal: bindUserReq{bindPage: bindPage{Page: 3, Size: 10}, ID: 123,
+				Name: "Tom", Tags: []string{"a"}},
+		},
+		{
+			name:        "multipart",
+			url:         "/user/123",
+			contentType: ct,
+			body:        body,
+			wantVal:     bindUserReq{ID: 123, Name: "Tom", Tags: []string{"a", "b"}},
+		},
+		{
+			name:    "no body",
+			url:     "/user/123?tag=c",
+			wantVal: bindUserReq{ID: 123, Tags: []string{"c"}},
+		},
+		{
+			name:        "unknown json field",
+			url:         "/user/123",
+			contentType: "application/json",
+			body:        bytes.NewBufferString(`{"nickname": "Tom"}`),
+			wantErr:     `json: unknown field "nickname"`,
+		},
+		{
+			name:        "unsupported content type",
+			url:         "/user/123",
+			contentType: "application/protobuf",
+			body:        bytes.NewBufferString(`abc`),
+			wantErr:     "web: 不支持的 Content-Type application/protobuf",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer()
+			var val bindUserReq
+			var err error
+			s.Post("/user/:id", func(ctx *Context) {
+				err = ctx.Bind(&val)
+			})
+			var req *http.Request
+			if tc.body != nil {
+				req = httptest.NewRequest(http.MethodPost, tc.url, tc.body)
+			} else {
+				req = httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(""))
+			}
+			req.Header.Set("Content-Type", tc.contentType)
+			s.ServeHTTP(httptest.NewRecorder(), req)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
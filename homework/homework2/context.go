@@ -3,9 +3,11 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 type Context struct {
@@ -30,6 +32,25 @@ type Context struct {
 
 	// 缓存的数据
 	cacheQueryValues url.Values
+
+	tplEngine TemplateEngine
+
+	// validationErrs BindValid 记录的校验错误
+	validationErrs ValidationErrors
+
+	// keys 请求级别的数据，参考 Set 和 Get
+	keys map[string]any
+
+	// errs 处理请求过程中的错误，参考 Error
+	errs []error
+
+	// stream 不为 nil 的时候，说明已经开始流式响应了
+	stream *Stream
+
+	// hijacked 连接已经被升级成 WebSocket 了，参考 Upgrade
+	hijacked bool
+	// wsConns 升级之后的 WebSocket 连接会记录在这里，HTTPServer 退出的时候关闭
+	wsConns *wsConns
 }
 
 func (c *Context) BindJSON(val any) error {
@@ -67,10 +88,110 @@ func (c *Context) PathValue(key string) StringValue {
 	return StringValue{val: val}
 }
 
+// QueryValues 返回查询参数的所有值，例如 ?id=1&id=2
+func (c *Context) QueryValues(key string) StringValues {
+	if c.cacheQueryValues == nil {
+		c.cacheQueryValues = c.Req.URL.Query()
+	}
+	vals, ok := c.cacheQueryValues[key]
+	if !ok {
+		return StringValues{err: errors.New("web: 找不到这个 key")}
+	}
+	return StringValues{vals: vals}
+}
+
+// FormValues 返回表单的所有值，和 FormValue 一样包含查询参数
+func (c *Context) FormValues(key string) StringValues {
+	if err := c.Req.ParseForm(); err != nil {
+		return StringValues{err: err}
+	}
+	vals, ok := c.Req.Form[key]
+	if !ok {
+		return StringValues{err: errors.New("web: 找不到这个 key")}
+	}
+	return StringValues{vals: vals}
+}
+
+// HeaderValue 返回请求头的第一个值，key 不区分大小写
+func (c *Context) HeaderValue(key string) StringValue {
+	vals := c.Req.Header.Values(key)
+	if len(vals) == 0 {
+		return StringValue{err: errors.New("web: 找不到这个 key")}
+	}
+	return StringValue{val: vals[0]}
+}
+
+// HeaderValues 返回请求头的所有值
+func (c *Context) HeaderValues(key string) StringValues {
+	vals := c.Req.Header.Values(key)
+	if len(vals) == 0 {
+		return StringValues{err: errors.New("web: 找不到这个 key")}
+	}
+	return StringValues{vals: vals}
+}
+
+// CookieValue 返回 cookie 的值
+func (c *Context) CookieValue(name string) StringValue {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return StringValue{err: err}
+	}
+	return StringValue{val: cookie.Value}
+}
+
 func (c *Context) SetCookie(cookie *http.Cookie) {
 	http.SetCookie(c.Resp, cookie)
 }
 
+// Set 保存请求级别的数据，例如 middleware 解析出来的用户信息，
+// 之后的 middleware 和 HandleFunc 都可以用 Get 读取。
+// 每个请求都有自己的 Context，所以数据不会在请求之间共享。
+// Context 不是并发安全的，如果要在别的 goroutine 里面使用，需要自己同步
+func (c *Context) Set(key string, val any) {
+	if c.keys == nil {
+		c.keys = make(map[string]any, 4)
+	}
+	c.keys[key] = val
+}
+
+func (c *Context) Get(key string) (any, bool) {
+	val, ok := c.keys[key]
+	return val, ok
+}
+
+// Delete 删除 Set 保存的数据，key 不存在的时候什么也不做
+func (c *Context) Delete(key string) {
+	delete(c.keys, key)
+}
+
+// MustGet 和 Get 一样，但是找不到 key 的时候会 panic
+func (c *Context) MustGet(key string) any {
+	val, ok := c.keys[key]
+	if !ok {
+		panic(fmt.Sprintf("web: 找不到 key %s", key))
+	}
+	return val
+}
+
+// GetAs 读取 Set 保存的数据，并且转换为 T
+// 找不到 key 或者类型不对的时候返回 false
+//
+//	user, ok := web.GetAs[*User](ctx, "user")
+func GetAs[T any](c *Context, key string) (T, bool) {
+	val, ok := c.keys[key].(T)
+	return val, ok
+}
+
+// MustGetAs 和 GetAs 一样，但是找不到 key 或者类型不对的时候会 panic
+func MustGetAs[T any](c *Context, key string) T {
+	val := c.MustGet(key)
+	res, ok := val.(T)
+	if !ok {
+		panic(fmt.Sprintf("web: key %s 的类型是 %T，不是 %T", key, val, res))
+	}
+	return res
+}
+
 func (c *Context) RespJSONOK(val any) error {
 	return c.RespJSON(http.StatusOK, val)
 }
@@ -85,6 +206,26 @@ func (c *Context) RespJSON(code int, val any) error {
 	return err
 }
 
+// Render 使用模板引擎渲染页面，渲染失败的时候响应 500
+func (c *Context) Render(tplName string, data any) error {
+	if c.tplEngine == nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		return errors.New("web: 没有设置模板引擎")
+	}
+	bs, err := c.tplEngine.Render(c.Req.Context(), tplName, data)
+	if err != nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		return err
+	}
+	// 用户已经设置了的话，就不覆盖，例如渲染的是 XML
+	if c.Resp.Header().Get("Content-Type") == "" {
+		c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	c.RespStatusCode = http.StatusOK
+	c.RespData = bs
+	return nil
+}
+
 // func (c *Context) QueryValueAsInt64(key string) (int64, error) {
 // 	val, err := c.QueryValue(key)
 // 	if err != nil {
@@ -109,6 +250,154 @@ func (s StringValue) ToInt64() (int64, error) {
 	return strconv.ParseInt(s.val, 10, 64)
 }
 
+func (s StringValue) ToInt() (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return strconv.Atoi(s.val)
+}
+
+func (s StringValue) ToUint64() (uint64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return strconv.ParseUint(s.val, 10, 64)
+}
+
+func (s StringValue) ToFloat64() (float64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return strconv.ParseFloat(s.val, 64)
+}
+
+// ToBool 支持 1、t、true、0、f、false 等，参考 strconv.ParseBool
+func (s StringValue) ToBool() (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return strconv.ParseBool(s.val)
+}
+
+// ToTime 按照 layout 解析时间，例如 time.RFC3339、"2006-01-02"
+// 没有时区信息的时候使用本地时区
+func (s StringValue) ToTime(layout string) (time.Time, error) {
+	if s.err != nil {
+		return time.Time{}, s.err
+	}
+	return time.ParseInLocation(layout, s.val, time.Local)
+}
+
+// ToDuration 解析 1s、1m30s 这种格式，参考 time.ParseDuration
+func (s StringValue) ToDuration() (time.Duration, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return time.ParseDuration(s.val)
+}
+
+// 下面这些方法在找不到 key 或者转换失败的时候返回默认值
+// 适用于分页参数之类的可选参数，例如 ctx.QueryValue("page").ToIntOr(1)
+
+func (s StringValue) StringOr(def string) string {
+	if s.err != nil {
+		return def
+	}
+	return s.val
+}
+
+func (s StringValue) ToInt64Or(def int64) int64 {
+	return valueOr(s.ToInt64, def)
+}
+
+func (s StringValue) ToIntOr(def int) int {
+	return valueOr(s.ToInt, def)
+}
+
+func (s StringValue) ToUint64Or(def uint64) uint64 {
+	return valueOr(s.ToUint64, def)
+}
+
+func (s StringValue) ToFloat64Or(def float64) float64 {
+	return valueOr(s.ToFloat64, def)
+}
+
+func (s StringValue) ToBoolOr(def bool) bool {
+	return valueOr(s.ToBool, def)
+}
+
+func (s StringValue) ToTimeOr(layout string, def time.Time) time.Time {
+	t, err := s.ToTime(layout)
+	if err != nil {
+		return def
+	}
+	return t
+}
+
+func (s StringValue) ToDurationOr(def time.Duration) time.Duration {
+	return valueOr(s.ToDuration, def)
+}
+
+func valueOr[T any](fn func() (T, error), def T) T {
+	val, err := fn()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// StringValues 多个值，例如 ?id=1&id=2
+// 转换的时候只要有一个值失败，就返回错误
+type StringValues struct {
+	vals []string
+	err  error
+}
+
+func (s StringValues) Strings() ([]string, error) {
+	return s.vals, s.err
+}
+
+func (s StringValues) ToInt64s() ([]int64, error) {
+	return convertValues(s, func(val string) (int64, error) {
+		return strconv.ParseInt(val, 10, 64)
+	})
+}
+
+func (s StringValues) ToInts() ([]int, error) {
+	return convertValues(s, strconv.Atoi)
+}
+
+func (s StringValues) ToUint64s() ([]uint64, error) {
+	return convertValues(s, func(val string) (uint64, error) {
+		return strconv.ParseUint(val, 10, 64)
+	})
+}
+
+func (s StringValues) ToFloat64s() ([]float64, error) {
+	return convertValues(s, func(val string) (float64, error) {
+		return strconv.ParseFloat(val, 64)
+	})
+}
+
+func (s StringValues) ToBools() ([]bool, error) {
+	return convertValues(s, strconv.ParseBool)
+}
+
+func convertValues[T any](s StringValues, fn func(val string) (T, error)) ([]T, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	res := make([]T, 0, len(s.vals))
+	for _, val := range s.vals {
+		v, err := fn(val)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
 // 不能用泛型
 // func (s StringValue) To[T any]() (T, error) {
 //
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStringValue(t *testing.T) {
+	notFound := StringValue{err: http.ErrNoCookie}
+	// 转换
+	i, err := StringValue{val: "12"}.ToInt()
+	require.NoError(t, err)
+	assert.Equal(t, 12, i)
+	u, err := StringValue{val: "18446744073709551615"}.ToUint64()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18446744073709551615), u)
+	f, err := StringValue{val: "1.5"}.ToFloat64()
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, f)
+	b, err := StringValue{val: "true"}.ToBool()
+	require.NoError(t, err)
+	assert.True(t, b)
+	tm, err := StringValue{val: "2022-11-01"}.ToTime("2006-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 11, 1, 0, 0, 0, 0, time.Local), tm)
+	d, err := StringValue{val: "1m30s"}.ToDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	// 错误
+	_, err = StringValue{val: "-1"}.ToUint64()
+	assert.EqualError(t, err, `strconv.ParseUint: parsing "-1": invalid syntax`)
+	_, err = StringValue{val: "yes"}.ToBool()
+	assert.EqualError(t, err, `strconv.ParseBool: parsing "yes": invalid syntax`)
+	_, err = notFound.ToInt()
+	assert.Equal(t, http.ErrNoCookie, err)
+	_, err = notFound.ToTime(time.RFC3339)
+	assert.Equal(t, http.ErrNoCookie, err)
+
+	// 默认值
+	def := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "abc", StringValue{val: "abc"}.StringOr("def"))
+	assert.Equal(t, "def", notFound.StringOr("def"))
+	assert.Equal(t, int64(3), StringValue{val: "3"}.ToInt64Or(1))
+	assert.Equal(t, int64(1), StringValue{val: "abc"}.ToInt64Or(1))
+	assert.Equal(t, 1, notFound.ToIntOr(1))
+	assert.Equal(t, uint64(1), notFound.ToUint64Or(1))
+	assert.Equal(t, 0.5, StringValue{val: "x"}.ToFloat64Or(0.5))
+	assert.True(t, notFound.ToBoolOr(true))
+	assert.Equal(t, def, StringValue{val: "2022"}.ToTimeOr(time.RFC3339, def))
+	assert.Equal(t, time.Second, notFound.ToDurationOr(time.Second))
+}
+
+func TestContext_Values(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/user?id=1&id=2&name=Tom&flag=1&flag=x",
+		strings.NewReader("score=1.5&score=2&id=3"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+	req.AddCookie(&http.Cookie{Name: "uid", Value: "123"})
+	ctx := &Context{Req: req}
+
+	ids, err := ctx.QueryValues("id").ToInt64s()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+	names, err := ctx.QueryValues("name").Strings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tom"}, names)
+	_, err = ctx.QueryValues("flag").ToBools()
+	assert.EqualError(t, err, `strconv.ParseBool: parsing "x": invalid syntax`)
+	_, err = ctx.QueryValues("age").ToInts()
+	assert.EqualError(t, err, "web: 找不到这个 key")
+
+	scores, err := ctx.FormValues("score").ToFloat64s()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1.5, 2}, scores)
+	// 表单的值排在查询参数前面
+	formIDs, err := ctx.FormValues("id").ToUint64s()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 1, 2}, formIDs)
+	_, err = ctx.FormValues("age").Strings()
+	assert.EqualError(t, err, "web: 找不到这个 key")
+
+	ip, err := ctx.HeaderValue("x-forwarded-for").String()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+	ips, err := ctx.HeaderValues("X-Forwarded-For").Strings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, ips)
+	_, err = ctx.HeaderValue("X-Token").String()
+	assert.EqualError(t, err, "web: 找不到这个 key")
+	_, err = ctx.HeaderValues("X-Token").Strings()
+	assert.EqualError(t, err, "web: 找不到这个 key")
+
+	uid, err := ctx.CookieValue("uid").ToInt64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), uid)
+	_, err = ctx.CookieValue("sessid").String()
+	assert.Equal(t, http.ErrNoCookie, err)
+}
+
+func TestContext_Keys(t *testing.T) {
+	type User struct {
+		ID int64
+	}
+	s := NewHTTPServer()
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			_, ok := ctx.Get("user")
+			// 每个请求都是新的
+			assert.False(t, ok)
+			if uid, err := ctx.HeaderValue("X-Uid").ToInt64(); err == nil {
+				ctx.Set("user", &User{ID: uid})
+			}
+			ctx.Set("trace_id", "trace-1")
+			next(ctx)
+		}
+	})
+	s.Get("/user", func(ctx *Context) {
+		user, ok := GetAs[*User](ctx, "user")
+		if !ok {
+			ctx.RespStatusCode = http.StatusUnauthorized
+			return
+		}
+		assert.Equal(t, "trace-1", ctx.MustGet("trace_id"))
+		assert.Equal(t, "trace-1", MustGetAs[string](ctx, "trace_id"))
+		_, ok = GetAs[int](ctx, "trace_id")
+		assert.False(t, ok)
+		ctx.RespData = []byte(strconv.FormatInt(user.ID, 10))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("X-Uid", "123")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, "123", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	ctx := &Context{}
+	_, ok := ctx.Get("user")
+	assert.False(t, ok)
+	assert.PanicsWithValue(t, "web: 找不到 key user", func() {
+		ctx.MustGet("user")
+	})
+	ctx.Set("user", "Tom")
+	assert.PanicsWithValue(t, "web: key user 的类型是 string，不是 int", func() {
+		MustGetAs[int](ctx, "user")
+	})
+	ctx.Delete("user")
+	_, ok = ctx.Get("user")
+	assert.False(t, ok)
+	// 删除不存在的 key 不会 panic
+	ctx.Delete("user")
+	(&Context{}).Delete("user")
+}
@@ -1,15 +1,23 @@
 package errhdl
 
-import web "homework/homework2"
+import (
+	"errors"
+	web "homework/homework2"
+	"log"
+	"net/http"
+)
 
 type MiddlewareBuilder struct {
 	resp map[int][]byte
+	// errRender 渲染 Context.Error 记录的错误
+	errRender func(ctx *web.Context, err error)
 }
 
 func NewMiddlewareBuilder() *MiddlewareBuilder {
 	return &MiddlewareBuilder{
 		// 这里可以非常大方，因为在预计中用户会关心的错误码不可能超过 64
-		resp: make(map[int][]byte, 64),
+		resp:      make(map[int][]byte, 64),
+		errRender: renderError,
 	}
 }
 
@@ -20,14 +28,66 @@ func (m *MiddlewareBuilder) RegisterError(code int, resp []byte) *MiddlewareBuil
 	return m
 }
 
+// ErrorRender 设置渲染错误的方法
+// 默认情况下 web.HTTPError 会被渲染成 {"code":..., "message":...}，
+// web.ValidationErrors 会被渲染成 400 {"errors":[...]}，
+// 其它错误会输出日志，然后返回 500，避免将内部的错误信息暴露出去
+func (m *MiddlewareBuilder) ErrorRender(render func(ctx *web.Context, err error)) *MiddlewareBuilder {
+	m.errRender = render
+	return m
+}
+
 func (m *MiddlewareBuilder) Build() web.Middleware {
 	return func(next web.HandleFunc) web.HandleFunc {
 		return func(ctx *web.Context) {
 			next(ctx)
+			// 有多个错误的时候，以最后一个为准
+			errs := ctx.Errors()
+			if len(errs) > 0 {
+				m.errRender(ctx, errs[len(errs)-1])
+			}
+			// 注册了的错误码优先，例如 404 页面
 			resp, ok := m.resp[ctx.RespStatusCode]
 			if ok {
 				ctx.RespData = resp
+				// 渲染错误的时候设置的 Content-Type 和注册的数据对不上了
+				if len(errs) > 0 {
+					ctx.Resp.Header().Del("Content-Type")
+				}
 			}
 		}
 	}
 }
+
+var internalErr = web.NewHTTPError(http.StatusInternalServerError, 0, "服务器内部错误")
+
+type validationResp struct {
+	Errors web.ValidationErrors `json:"errors"`
+}
+
+// renderError 根据错误的类型决定响应
+// 和 validation 包的 middleware 一起使用的时候，需要把 validation 的 middleware 注册在外面，
+// 这样它的响应才会覆盖这里的 400
+func renderError(ctx *web.Context, err error) {
+	// 校验失败是客户端的错误，和 validation 包的 middleware 一样返回 400
+	var verrs web.ValidationErrors
+	if errors.As(err, &verrs) {
+		ctx.Resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = ctx.RespJSON(http.StatusBadRequest, validationResp{Errors: verrs})
+		return
+	}
+	var he *web.HTTPError
+	if !errors.As(err, &he) {
+		log.Printf("web: %s %s 出错 %v", ctx.Req.Method, ctx.Req.URL.Path, err)
+		he = internalErr
+	} else if he.Err != nil {
+		log.Printf("web: %s %s 出错 %v", ctx.Req.Method, ctx.Req.URL.Path, he)
+	}
+	status := he.Status
+	// 没有设置响应码的 HTTPError 也是出错了，不能返回 200
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	ctx.Resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = ctx.RespJSON(status, he)
+}
@@ -2,8 +2,13 @@ package errhdl
 
 import (
 	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
 	web "homework/homework2"
+	"homework/homework2/validation"
 	"html/template"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -31,3 +36,132 @@ func TestNewMiddlewareBuilder(t *testing.T) {
 
 	s.Start(":8081")
 }
+
+func TestMiddlewareBuilder_Errors(t *testing.T) {
+	errUserNotFound := web.NewHTTPError(http.StatusNotFound, 10001, "用户不存在")
+	errNoPermission := web.NewHTTPError(http.StatusForbidden, 10002, "没有权限")
+	s := web.NewHTTPServer()
+	s.Get("/user", web.HandleE(func(ctx *web.Context) error {
+		return errUserNotFound.WithErr(errors.New("record not found"))
+	}))
+	s.Get("/order", web.HandleE(func(ctx *web.Context) error {
+		return errNoPermission
+	}))
+	s.Get("/internal", web.HandleE(func(ctx *web.Context) error {
+		ctx.RespData = []byte("部分数据")
+		return errors.New("dial tcp: connection refused")
+	}))
+	s.Get("/no-status", web.HandleE(func(ctx *web.Context) error {
+		return &web.HTTPError{Code: 10003, Message: "没有响应码"}
+	}))
+	s.Get("/ok", web.HandleE(func(ctx *web.Context) error {
+		return ctx.RespJSONOK("ok")
+	}))
+	type signupReq struct {
+		Email string `query:"email" json:"email" validate:"email"`
+	}
+	s.Get("/signup", web.HandleE(func(ctx *web.Context) error {
+		var req signupReq
+		return ctx.BindValid(&req)
+	}))
+	s.Use(NewMiddlewareBuilder().RegisterError(http.StatusForbidden, []byte("403 page")).Build())
+
+	// 和 validation 包的 middleware 一起使用
+	withValidation := web.NewHTTPServer()
+	withValidation.Get("/signup", web.HandleE(func(ctx *web.Context) error {
+		var req signupReq
+		return ctx.BindValid(&req)
+	}))
+	// validation 的 middleware 在外面，覆盖 errhdl 的响应
+	withValidation.Use(validation.NewBuilder().StatusCode(http.StatusUnprocessableEntity).Build(), NewMiddlewareBuilder().Build())
+
+	custom := web.NewHTTPServer()
+	custom.Get("/user", web.HandleE(func(ctx *web.Context) error {
+		return errUserNotFound
+	}))
+	custom.Use(NewMiddlewareBuilder().ErrorRender(func(ctx *web.Context, err error) {
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte(err.Error())
+	}).Build())
+
+	testCases := []struct {
+		name   string
+		server *web.HTTPServer
+		path   string
+
+		wantCode int
+		wantResp string
+		wantType string
+	}{
+		{
+			name:     "http error",
+			server:   s,
+			path:     "/user",
+			wantCode: http.StatusNotFound,
+			wantResp: `{"code":10001,"message":"用户不存在"}`,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "registered",
+			server:   s,
+			path:     "/order",
+			wantCode: http.StatusForbidden,
+			wantResp: "403 page",
+		},
+		{
+			name:     "validation",
+			server:   s,
+			path:     "/signup?email=abc",
+			wantCode: http.StatusBadRequest,
+			wantResp: `{"errors":[{"field":"email","rule":"email","message":"不是合法的邮箱"}]}`,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "with validation middleware",
+			server:   withValidation,
+			path:     "/signup?email=abc",
+			wantCode: http.StatusUnprocessableEntity,
+			wantResp: `{"errors":[{"field":"email","rule":"email","message":"不是合法的邮箱"}]}`,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "internal",
+			server:   s,
+			path:     "/internal",
+			wantCode: http.StatusInternalServerError,
+			wantResp: `{"code":500,"message":"服务器内部错误"}`,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "no status",
+			server:   s,
+			path:     "/no-status",
+			wantCode: http.StatusInternalServerError,
+			wantResp: `{"code":10003,"message":"没有响应码"}`,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "ok",
+			server:   s,
+			path:     "/ok",
+			wantCode: http.StatusOK,
+			wantResp: `"ok"`,
+		},
+		{
+			name:     "custom render",
+			server:   custom,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantResp: "web: 404 10001 用户不存在",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			assert.Equal(t, tc.wantType, recorder.Header().Get("Content-Type"))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPError 带有 HTTP 响应码和业务错误码的错误
+// HandleFunc 可以通过 Context.Error 或者 HandleE 返回，
+// 由 errhdl 里面的 middleware 统一渲染成 {"code":..., "message":...}
+type HTTPError struct {
+	// Status HTTP 响应码，为 0 的时候 errhdl 会返回 500
+	Status int `json:"-"`
+	// Code 业务错误码，前端可以根据它来展示不同的提示
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Err 原始的错误，只用于记录日志，不会返回给前端
+	Err error `json:"-"`
+}
+
+// NewHTTPError code 是业务错误码，为 0 的时候使用 status
+//
+//	var ErrUserNotFound = web.NewHTTPError(http.StatusNotFound, 10001, "用户不存在")
+func NewHTTPError(status int, code int, msg string) *HTTPError {
+	if code == 0 {
+		code = status
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Code: code, Message: msg}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("web: %d %d %s: %v", e.Status, e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("web: %d %d %s", e.Status, e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Is 响应码和业务错误码一样，就认为是同一个错误，
+// 所以 errors.Is(err, ErrUserNotFound) 不受 WithErr 的影响
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t.Status == e.Status && t.Code == e.Code
+}
+
+// WithErr 返回一个带上原始错误的副本，不会修改 e 本身，
+// 所以可以放心地在预定义的错误上调用
+func (e *HTTPError) WithErr(err error) *HTTPError {
+	res := *e
+	res.Err = err
+	return &res
+}
+
+// HandleFuncE 返回 error 的 HandleFunc
+type HandleFuncE func(ctx *Context) error
+
+// HandleE 将 HandleFuncE 转换为 HandleFunc，返回的 error 会通过 Context.Error 记录下来
+//
+//	s.Get("/user/:id", web.HandleE(func(ctx *web.Context) error {
+//		user, err := findUser(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		return ctx.RespJSONOK(user)
+//	}))
+func HandleE(fn HandleFuncE) HandleFunc {
+	return func(ctx *Context) {
+		if err := fn(ctx); err != nil {
+			ctx.Error(err)
+		}
+	}
+}
+
+// Error 记录处理请求过程中的错误
+// 错误不会直接影响响应，需要 errhdl 之类的 middleware 来处理
+func (c *Context) Error(err error) {
+	if err == nil {
+		return
+	}
+	c.errs = append(c.errs, err)
+}
+
+// Errors 返回 Error 记录的所有错误，按照记录的顺序排列
+func (c *Context) Errors() []error {
+	return c.errs
+}
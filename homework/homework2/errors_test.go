@@ -0,0 +1,57 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	errUserNotFound := NewHTTPError(http.StatusNotFound, 10001, "用户不存在")
+	assert.EqualError(t, errUserNotFound, "web: 404 10001 用户不存在")
+
+	dbErr := errors.New("record not found")
+	err := errUserNotFound.WithErr(dbErr)
+	assert.EqualError(t, err, "web: 404 10001 用户不存在: record not found")
+	assert.True(t, errors.Is(err, errUserNotFound))
+	assert.True(t, errors.Is(err, dbErr))
+	// 不会修改原本的错误
+	assert.Nil(t, errUserNotFound.Err)
+	assert.False(t, errors.Is(err, NewHTTPError(http.StatusNotFound, 10002, "订单不存在")))
+
+	badReq := NewHTTPError(http.StatusBadRequest, 0, "")
+	assert.Equal(t, &HTTPError{Status: http.StatusBadRequest, Code: http.StatusBadRequest,
+		Message: "Bad Request"}, badReq)
+}
+
+func TestHandleE(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	s := NewHTTPServer()
+	s.Get("/ok", HandleE(func(ctx *Context) error {
+		return ctx.RespJSONOK(map[string]string{"name": "Tom"})
+	}))
+	s.Get("/error", HandleE(func(ctx *Context) error {
+		ctx.Error(errFirst)
+		ctx.Error(nil)
+		return errSecond
+	}))
+	var errs []error
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			errs = ctx.Errors()
+		}
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, `{"name":"Tom"}`, recorder.Body.String())
+	assert.Nil(t, errs)
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Equal(t, []error{errFirst, errSecond}, errs)
+}
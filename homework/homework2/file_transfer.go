@@ -0,0 +1,272 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileUploader 处理文件上传
+// 文件是直接从请求体写到磁盘上的，不会把整个文件读到内存里面
+//
+//	u := web.NewFileUploader("file", func(filename string) string {
+//		return filepath.Join("./upload", filename)
+//	}, web.UploadWithMaxSize(10<<20), web.UploadWithExtensions(".png", ".jpg"))
+//	s.Post("/upload", u.Handle())
+type FileUploader struct {
+	// fileField multipart 表单里面文件的字段名
+	fileField string
+	// dstPathFunc 根据上传的文件名计算目标路径
+	// 传入的文件名已经去掉了目录部分
+	dstPathFunc func(filename string) string
+	// maxSize 文件大小的上限，0 表示不限制
+	maxSize int64
+	// allowedExts 允许的扩展名，为空表示不限制
+	allowedExts map[string]struct{}
+}
+
+type FileUploaderOption func(u *FileUploader)
+
+func NewFileUploader(fileField string, dstPathFunc func(filename string) string,
+	opts ...FileUploaderOption) *FileUploader {
+	res := &FileUploader{
+		fileField:   fileField,
+		dstPathFunc: dstPathFunc,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// UploadWithMaxSize 限制文件大小，单位是字节
+// 超过的时候返回 413，已经写入的部分会被删除
+func UploadWithMaxSize(maxSize int64) FileUploaderOption {
+	return func(u *FileUploader) {
+		u.maxSize = maxSize
+	}
+}
+
+// UploadWithExtensions 只允许上传这些扩展名的文件，不区分大小写
+// 扩展名需要带上 .，例如 ".png"。其它扩展名的文件返回 415
+func UploadWithExtensions(exts ...string) FileUploaderOption {
+	return func(u *FileUploader) {
+		u.allowedExts = make(map[string]struct{}, len(exts))
+		for _, ext := range exts {
+			u.allowedExts[strings.ToLower(ext)] = struct{}{}
+		}
+	}
+}
+
+var errFileTooLarge = errors.New("web: 文件太大")
+
+func (u *FileUploader) Handle() HandleFunc {
+	return func(ctx *Context) {
+		// Content-Length 已经超过了，那么就没有必要读了
+		if u.maxSize > 0 && ctx.Req.ContentLength > 0 &&
+			ctx.Req.ContentLength > u.maxSize+(1<<20) {
+			ctx.RespStatusCode = http.StatusRequestEntityTooLarge
+			ctx.RespData = []byte("文件太大")
+			return
+		}
+		reader, err := ctx.Req.MultipartReader()
+		if err != nil {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("请求不是 multipart 表单")
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				ctx.RespStatusCode = http.StatusBadRequest
+				ctx.RespData = []byte("找不到上传的文件")
+				return
+			}
+			if err != nil {
+				ctx.RespStatusCode = http.StatusBadRequest
+				ctx.RespData = []byte("读取表单失败")
+				return
+			}
+			if part.FormName() != u.fileField || part.FileName() == "" {
+				_ = part.Close()
+				continue
+			}
+			// 客户端可能会在文件名里面带上路径
+			filename := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(part.FileName(), `\`, "/")))
+			// 清理之后只剩下根目录，例如 . 和 ..
+			if filename == string(filepath.Separator) || filename == "." {
+				ctx.RespStatusCode = http.StatusBadRequest
+				ctx.RespData = []byte("非法的文件名")
+				return
+			}
+			if !u.allowed(filename) {
+				ctx.RespStatusCode = http.StatusUnsupportedMediaType
+				ctx.RespData = []byte("不支持的文件类型")
+				return
+			}
+			err = u.save(part, u.dstPathFunc(filename))
+			_ = part.Close()
+			if err == errFileTooLarge {
+				ctx.RespStatusCode = http.StatusRequestEntityTooLarge
+				ctx.RespData = []byte("文件太大")
+				return
+			}
+			if err != nil {
+				ctx.RespStatusCode = http.StatusInternalServerError
+				ctx.RespData = []byte("上传失败")
+				return
+			}
+			ctx.RespStatusCode = http.StatusOK
+			ctx.RespData = []byte("上传成功")
+			return
+		}
+	}
+}
+
+func (u *FileUploader) allowed(filename string) bool {
+	if len(u.allowedExts) == 0 {
+		return true
+	}
+	_, ok := u.allowedExts[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// save 把文件写到 dst
+// 先写到同一个目录下的临时文件里面，成功之后再重命名，
+// 这样出错的时候不会破坏已经存在的同名文件
+func (u *FileUploader) save(src io.Reader, dst string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), dst)
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	// CreateTemp 创建的文件只有自己可以读写，和普通文件保持一致
+	if err = f.Chmod(0o644); err != nil {
+		return err
+	}
+	if u.maxSize <= 0 {
+		_, err = io.Copy(f, src)
+		return err
+	}
+	// 多读一个字节，用来判断是不是超过了上限
+	n, err := io.Copy(f, io.LimitReader(src, u.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if n > u.maxSize {
+		return errFileTooLarge
+	}
+	return nil
+}
+
+// FileDownloader 处理文件下载
+// 从查询参数里面读取文件名，只允许下载 dir 目录下的文件
+//
+//	d := web.NewFileDownloader("./download")
+//	s.Get("/download", d.Handle())
+//
+// 请求 /download?file=a/b.txt 会下载 ./download/a/b.txt
+type FileDownloader struct {
+	dir string
+	// queryKey 文件名的查询参数，默认是 file
+	queryKey string
+}
+
+type FileDownloaderOption func(d *FileDownloader)
+
+func NewFileDownloader(dir string, opts ...FileDownloaderOption) *FileDownloader {
+	res := &FileDownloader{
+		dir:      dir,
+		queryKey: "file",
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// DownloadWithQueryKey 指定文件名的查询参数
+func DownloadWithQueryKey(key string) FileDownloaderOption {
+	return func(d *FileDownloader) {
+		d.queryKey = key
+	}
+}
+
+func (d *FileDownloader) Handle() HandleFunc {
+	return func(ctx *Context) {
+		req, err := ctx.QueryValue(d.queryKey).String()
+		if err != nil || req == "" {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("找不到目标文件")
+			return
+		}
+		dst, err := d.path(req)
+		if err != nil {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("非法的文件路径")
+			return
+		}
+		f, err := os.Open(dst)
+		if err != nil {
+			ctx.RespStatusCode = http.StatusNotFound
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			ctx.RespStatusCode = http.StatusNotFound
+			return
+		}
+		header := ctx.Resp.Header()
+		// 非 ASCII 的文件名会按照 RFC 2231 编码成 filename*
+		header.Set("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+		header.Set("Content-Type", "application/octet-stream")
+		// 文件可能很大，所以直接写到 Resp 里面，不经过 RespData
+		// ServeContent 也顺便支持了 Range 和 Last-Modified
+		http.ServeContent(headerWriter{ResponseWriter: ctx.Resp, ctx: ctx},
+			ctx.Req, info.Name(), info.ModTime(), f)
+	}
+}
+
+// headerWriter 把 WriteHeader 的响应码记录到 Context 上
+// 用于 http.ServeContent 之类直接写响应的场景
+type headerWriter struct {
+	http.ResponseWriter
+	ctx *Context
+}
+
+func (w headerWriter) WriteHeader(status int) {
+	w.ctx.writeHeader(status)
+}
+
+// path 计算文件的路径，确保不会跳出 dir
+func (d *FileDownloader) path(req string) (string, error) {
+	root, err := filepath.Abs(d.dir)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(root, filepath.FromSlash(filepath.Clean("/"+req)))
+	if dst != root && !strings.HasPrefix(dst, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("web: 非法的文件路径 %s", req)
+	}
+	return dst, nil
+}
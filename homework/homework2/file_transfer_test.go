@@ -0,0 +1,268 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileUploader_Handle(t *testing.T) {
+	dir := t.TempDir()
+	u := NewFileUploader("myfile", func(filename string) string {
+		return filepath.Join(dir, "upload", filename)
+	}, UploadWithMaxSize(10), UploadWithExtensions(".txt", ".PNG"))
+	s := NewHTTPServer()
+	s.Post("/upload", u.Handle())
+
+	type field struct {
+		name     string
+		filename string
+		content  string
+	}
+	newReq := func(fields ...field) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, f := range fields {
+			if f.filename == "" {
+				require.NoError(t, writer.WriteField(f.name, f.content))
+				continue
+			}
+			w, err := writer.CreateFormFile(f.name, f.filename)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(f.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	testCases := []struct {
+		name string
+		req  *http.Request
+
+		wantCode int
+		wantResp string
+		// wantFile 上传之后的文件，为空的话表示不应该有文件
+		wantFile    string
+		wantContent string
+	}{
+		{
+			name: "upload",
+			req: newReq(field{name: "id", content: "123"},
+				field{name: "myfile", filename: "a.txt", content: "hello"}),
+			wantCode:    http.StatusOK,
+			wantResp:    "上传成功",
+			wantFile:    "a.txt",
+			wantContent: "hello",
+		},
+		{
+			name:        "extension case insensitive",
+			req:         newReq(field{name: "myfile", filename: "b.png", content: "png"}),
+			wantCode:    http.StatusOK,
+			wantResp:    "上传成功",
+			wantFile:    "b.png",
+			wantContent: "png",
+		},
+		{
+			name:        "traversal",
+			req:         newReq(field{name: "myfile", filename: "../../c.txt", content: "c"}),
+			wantCode:    http.StatusOK,
+			wantResp:    "上传成功",
+			wantFile:    "c.txt",
+			wantContent: "c",
+		},
+		{
+			name:     "too large",
+			req:      newReq(field{name: "myfile", filename: "d.txt", content: "01234567890"}),
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantResp: "文件太大",
+			wantFile: "",
+		},
+		{
+			// 上传失败的时候不会破坏已经存在的文件
+			name:        "too large overwrite",
+			req:         newReq(field{name: "myfile", filename: "a.txt", content: "01234567890"}),
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantResp:    "文件太大",
+			wantFile:    "a.txt",
+			wantContent: "hello",
+		},
+		{
+			name:     "dot dot",
+			req:      newReq(field{name: "myfile", filename: "..", content: "dir"}),
+			wantCode: http.StatusBadRequest,
+			wantResp: "非法的文件名",
+		},
+		{
+			name:     "extension not allowed",
+			req:      newReq(field{name: "myfile", filename: "e.exe", content: "exe"}),
+			wantCode: http.StatusUnsupportedMediaType,
+			wantResp: "不支持的文件类型",
+		},
+		{
+			name:     "no file",
+			req:      newReq(field{name: "other", filename: "f.txt", content: "f"}),
+			wantCode: http.StatusBadRequest,
+			wantResp: "找不到上传的文件",
+		},
+		{
+			name: "not multipart",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			}(),
+			wantCode: http.StatusBadRequest,
+			wantResp: "请求不是 multipart 表单",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, tc.req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			if tc.wantFile == "" {
+				return
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "upload", tc.wantFile))
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantContent, string(data))
+		})
+	}
+	// 超过大小的文件和临时文件都不会留在磁盘上
+	entries, err := os.ReadDir(filepath.Join(dir, "upload"))
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"a.txt", "b.png", "c.txt"}, names)
+}
+
+func TestFileDownloader_Handle(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "download")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("hello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "报告.txt"), []byte("report"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644))
+
+	s := NewHTTPServer()
+	var status int
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = ctx.RespStatusCode
+		}
+	})
+	s.Get("/download", NewFileDownloader(dir).Handle())
+	s.Get("/download2", NewFileDownloader(dir, DownloadWithQueryKey("name")).Handle())
+
+	testCases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+
+		wantCode        int
+		wantResp        string
+		wantDisposition string
+	}{
+		{
+			name:            "download",
+			path:            "/download?file=sub/a.txt",
+			wantCode:        http.StatusOK,
+			wantResp:        "hello",
+			wantDisposition: "attachment; filename=a.txt",
+		},
+		{
+			name:            "non ascii",
+			path:            "/download?file=%E6%8A%A5%E5%91%8A.txt",
+			wantCode:        http.StatusOK,
+			wantResp:        "report",
+			wantDisposition: "attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A.txt",
+		},
+		{
+			name:            "range",
+			path:            "/download?file=sub/a.txt",
+			headers:         map[string]string{"Range": "bytes=1-2"},
+			wantCode:        http.StatusPartialContent,
+			wantResp:        "el",
+			wantDisposition: "attachment; filename=a.txt",
+		},
+		{
+			name:            "query key",
+			path:            "/download2?name=sub/a.txt",
+			wantCode:        http.StatusOK,
+			wantResp:        "hello",
+			wantDisposition: "attachment; filename=a.txt",
+		},
+		{
+			name:     "traversal",
+			path:     "/download?file=../secret.txt",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "dir",
+			path:     "/download?file=sub",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "not found",
+			path:     "/download?file=b.txt",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "no file",
+			path:     "/download",
+			wantCode: http.StatusBadRequest,
+			wantResp: "找不到目标文件",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantCode, status)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			assert.Equal(t, tc.wantDisposition, recorder.Header().Get("Content-Disposition"))
+		})
+	}
+}
+
+func TestFileDownloader_path(t *testing.T) {
+	d := NewFileDownloader("/data/download")
+	testCases := []struct {
+		req     string
+		want    string
+		wantErr bool
+	}{
+		{req: "a.txt", want: "/data/download/a.txt"},
+		{req: "../a.txt", want: "/data/download/a.txt"},
+		{req: "/../../etc/passwd", want: "/data/download/etc/passwd"},
+		{req: "a/../../b.txt", want: "/data/download/b.txt"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.req, func(t *testing.T) {
+			got, err := d.path(tc.req)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package web
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticResourceHandler 处理静态资源
+// 需要配合通配符路由使用，例如：
+//
+//	h := web.NewStaticResourceHandler("./static")
+//	s.Get("/static/*filepath", h.Handle)
+//
+// 支持 ETag、Last-Modified 等缓存协商，以及单个区间的 Range 请求
+type StaticResourceHandler struct {
+	fsys fs.FS
+	// pathParam 通配符参数的名字，默认是 filepath
+	pathParam string
+	// extContentTypeMap 扩展名到 Content-Type 的映射
+	// 找不到的时候使用 mime.TypeByExtension，再找不到就根据内容判断
+	extContentTypeMap map[string]string
+
+	// cache 缓存小文件的内容，为 nil 的时候不缓存
+	cache *fileCache
+	// maxCacheFileSize 能够被缓存的最大文件大小
+	maxCacheFileSize int
+}
+
+type StaticResourceHandlerOption func(h *StaticResourceHandler)
+
+// NewStaticResourceHandler 使用 dir 目录下的文件
+func NewStaticResourceHandler(dir string, opts ...StaticResourceHandlerOption) *StaticResourceHandler {
+	return NewStaticResourceHandlerFS(os.DirFS(dir), opts...)
+}
+
+// NewStaticResourceHandlerFS 使用 fs.FS 里面的文件，可以是 embed.FS
+// 如果 embed.FS 里面的文件都在某个目录下，可以先使用 fs.Sub
+func NewStaticResourceHandlerFS(fsys fs.FS, opts ...StaticResourceHandlerOption) *StaticResourceHandler {
+	res := &StaticResourceHandler{
+		fsys:      fsys,
+		pathParam: "filepath",
+		extContentTypeMap: map[string]string{
+			".js":   "application/javascript",
+			".css":  "text/css; charset=utf-8",
+			".html": "text/html; charset=utf-8",
+			".json": "application/json",
+			".svg":  "image/svg+xml",
+			".png":  "image/png",
+			".jpg":  "image/jpeg",
+			".jpeg": "image/jpeg",
+			".pdf":  "application/pdf",
+		},
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// StaticWithPathParam 指定通配符参数的名字，
+// 例如路由是 /assets/*name 的时候，使用 StaticWithPathParam("name")
+func StaticWithPathParam(name string) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		h.pathParam = name
+	}
+}
+
+// StaticWithCache 开启缓存
+// maxFileSize 是能够被缓存的最大文件大小，maxCount 是最多缓存多少个文件
+// 超过 maxCount 的时候，淘汰最久没有被访问的文件
+// 每次请求都会比较文件的修改时间和大小，文件被修改之后缓存会失效
+func StaticWithCache(maxFileSize int, maxCount int) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		h.maxCacheFileSize = maxFileSize
+		h.cache = newFileCache(maxCount)
+	}
+}
+
+// StaticWithExtension 追加或者覆盖扩展名到 Content-Type 的映射
+// 扩展名需要带上 .，例如 ".wasm"
+func StaticWithExtension(extMap map[string]string) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		for ext, contentType := range extMap {
+			h.extContentTypeMap[ext] = contentType
+		}
+	}
+}
+
+// fileItem 文件的元数据，小文件还会带上内容
+type fileItem struct {
+	name        string
+	size        int64
+	modTime     time.Time
+	etag        string
+	contentType string
+	// data 能够被缓存的小文件的内容，大文件是 nil，需要边读边写
+	data []byte
+}
+
+func (h *StaticResourceHandler) Handle(ctx *Context) {
+	// 清理掉 .. 之类的，避免访问到目录之外的文件
+	name := path.Clean("/" + ctx.PathParams[h.pathParam])[1:]
+	if name == "" || !fs.ValidPath(name) {
+		ctx.RespStatusCode = http.StatusNotFound
+		return
+	}
+	item, f, err := h.openFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			ctx.RespStatusCode = http.StatusNotFound
+			return
+		}
+		ctx.RespStatusCode = http.StatusInternalServerError
+		return
+	}
+	defer f.Close()
+	header := ctx.Resp.Header()
+	header.Set("Content-Type", item.contentType)
+	if item.etag != "" {
+		header.Set("ETag", item.etag)
+	}
+	header.Set("Accept-Ranges", "bytes")
+	if !item.modTime.IsZero() {
+		header.Set("Last-Modified", item.modTime.UTC().Format(http.TimeFormat))
+	}
+	if item.notModified(ctx.Req) {
+		ctx.RespStatusCode = http.StatusNotModified
+		return
+	}
+	h.writeRange(ctx, item, f)
+}
+
+// openFile 打开文件，返回的 fs.File 需要调用者关闭
+// 只有开启了缓存，并且不超过 maxCacheFileSize 的文件才会被读到内存里面，
+// 其余的文件在响应的时候直接从 fs.File 里面读，避免大文件耗尽内存
+func (h *StaticResourceHandler) openFile(name string) (*fileItem, fs.File, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	item, err := h.fileItem(name, f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return item, f, nil
+}
+
+func (h *StaticResourceHandler) fileItem(name string, f fs.File) (*fileItem, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// 不支持列出目录
+	if info.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+	if h.cache != nil {
+		// 文件被修改过的话，缓存就失效了
+		// embed.FS 里面的文件没有修改时间，但是它们也不会被修改
+		if item, ok := h.cache.get(name); ok &&
+			item.modTime.Equal(info.ModTime()) && item.size == info.Size() {
+			return item, nil
+		}
+	}
+	item := &fileItem{
+		name:    name,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+	rs, seekable := f.(io.ReadSeeker)
+	if (h.cache != nil && info.Size() <= int64(h.maxCacheFileSize)) || !seekable {
+		// 不支持 Seek 的文件没办法处理 Range，只能读到内存里面
+		item.data, err = io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		item.size = int64(len(item.data))
+		sum := sha256.Sum256(item.data)
+		item.etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+		item.contentType = h.contentType(name, item.data)
+		if h.cache != nil && seekable {
+			h.cache.add(name, item)
+		}
+		return item, nil
+	}
+
+	// 大文件用修改时间和大小作为 ETag，
+	// 没有修改时间的，例如 embed.FS，只能读一遍计算摘要，但是不会占用内存
+	if !item.modTime.IsZero() {
+		item.etag = fmt.Sprintf(`"%x-%x"`, item.modTime.UnixNano(), item.size)
+	} else {
+		hash := sha256.New()
+		if _, err = io.Copy(hash, rs); err != nil {
+			return nil, err
+		}
+		item.etag = `"` + hex.EncodeToString(hash.Sum(nil)[:8]) + `"`
+	}
+	// 只需要开头的一部分就可以判断 Content-Type
+	head := make([]byte, 512)
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	n, err := io.ReadFull(rs, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	item.contentType = h.contentType(name, head[:n])
+	return item, nil
+}
+
+func (h *StaticResourceHandler) contentType(name string, data []byte) string {
+	ext := path.Ext(name)
+	if contentType, ok := h.extContentTypeMap[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
+
+// notModified 判断是否可以返回 304
+// 和 RFC 7232 一致，有 If-None-Match 的时候忽略 If-Modified-Since
+func (f *fileItem) notModified(req *http.Request) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, f.etag)
+	}
+	ims := req.Header.Get("If-Modified-Since")
+	if ims == "" || f.modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP 的时间只精确到秒
+	return !f.modTime.Truncate(time.Second).After(t)
+}
+
+// ifRangeMatch 判断 If-Range 是否命中
+// If-Range 可以是 ETag，也可以是 Last-Modified 的时间，两者都必须完全一致
+func (f *fileItem) ifRangeMatch(ifRange string) bool {
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return ifRange == f.etag
+	}
+	if f.modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return f.modTime.Truncate(time.Second).Equal(t)
+}
+
+// etagMatch 判断 If-None-Match 是否命中，使用弱比较
+func etagMatch(header string, etag string) bool {
+	for _, val := range strings.Split(header, ",") {
+		val = strings.TrimSpace(val)
+		if val == "*" || strings.TrimPrefix(val, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRange 处理 Range 请求
+// 只支持单个区间，多个区间的时候返回整个文件，这也是 RFC 7233 允许的
+func (h *StaticResourceHandler) writeRange(ctx *Context, item *fileItem, f fs.File) {
+	rangeHeader := ctx.Req.Header.Get("Range")
+	// If-Range 不匹配说明客户端手里的文件已经过时了，需要返回整个文件
+	if ifRange := ctx.Req.Header.Get("If-Range"); ifRange != "" && !item.ifRangeMatch(ifRange) {
+		rangeHeader = ""
+	}
+	if rangeHeader == "" || strings.Contains(rangeHeader, ",") {
+		h.writeContent(ctx, item, f, http.StatusOK, 0, item.size-1)
+		return
+	}
+	start, end, ok := parseRange(rangeHeader, item.size)
+	if !ok {
+		ctx.Resp.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", item.size))
+		ctx.RespStatusCode = http.StatusRequestedRangeNotSatisfiable
+		return
+	}
+	ctx.Resp.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, item.size))
+	h.writeContent(ctx, item, f, http.StatusPartialContent, start, end)
+}
+
+// writeContent 写入 [start, end] 之间的内容
+// 小文件通过 RespData 返回，大文件直接写到 Resp 里面，不经过 RespData
+func (h *StaticResourceHandler) writeContent(ctx *Context, item *fileItem,
+	f fs.File, status int, start int64, end int64) {
+	if item.data != nil {
+		ctx.RespStatusCode = status
+		// 限制容量，避免后面的 middleware append 的时候改掉缓存的内容
+		ctx.RespData = item.data[start : end+1 : end+1]
+		return
+	}
+	rs := f.(io.ReadSeeker)
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		ctx.RespStatusCode = http.StatusInternalServerError
+		return
+	}
+	ctx.Resp.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	ctx.writeHeader(status)
+	if ctx.Req.Method != http.MethodHead {
+		_, _ = io.CopyN(ctx.Resp, rs, end-start+1)
+	}
+}
+
+// parseRange 解析 bytes=start-end，返回的 end 是包含在内的
+// 支持 bytes=start- 和 bytes=-suffix 两种省略的写法
+func parseRange(header string, size int64) (int64, int64, bool) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false
+	}
+	spec := strings.TrimSpace(header[len("bytes="):])
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	if startStr == "" {
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, size - 1, true
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+// fileCache 并发安全的 LRU 缓存
+type fileCache struct {
+	mutex    sync.Mutex
+	maxCount int
+	lst      *list.List
+	items    map[string]*list.Element
+}
+
+func newFileCache(maxCount int) *fileCache {
+	return &fileCache{
+		maxCount: maxCount,
+		lst:      list.New(),
+		items:    make(map[string]*list.Element, maxCount),
+	}
+}
+
+func (c *fileCache) get(name string) (*fileItem, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ele, ok := c.items[name]
+	if !ok {
+		return nil, false
+	}
+	c.lst.MoveToFront(ele)
+	return ele.Value.(*fileItem), true
+}
+
+func (c *fileCache) add(name string, item *fileItem) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if ele, ok := c.items[name]; ok {
+		ele.Value = item
+		c.lst.MoveToFront(ele)
+		return
+	}
+	c.items[name] = c.lst.PushFront(item)
+	for c.lst.Len() > c.maxCount {
+		last := c.lst.Back()
+		c.lst.Remove(last)
+		delete(c.items, last.Value.(*fileItem).name)
+	}
+}
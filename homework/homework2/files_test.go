@@ -0,0 +1,337 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticResourceHandler_Handle(t *testing.T) {
+	modTime := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<html></html>"), ModTime: modTime},
+		"js/app.js":     {Data: []byte("console.log(1)"), ModTime: modTime},
+		"data.txt":      {Data: []byte("0123456789"), ModTime: modTime},
+		"file.unknown1": {Data: []byte("hello"), ModTime: modTime},
+	}
+	h := NewStaticResourceHandlerFS(fsys)
+	s := NewHTTPServer()
+	s.Get("/static/*filepath", h.Handle)
+
+	etag := func(name string) string {
+		item, f, err := h.openFile(name)
+		require.NoError(t, err)
+		_ = f.Close()
+		return item.etag
+	}
+
+	testCases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+
+		wantCode    int
+		wantResp    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:     "html",
+			path:     "/static/index.html",
+			wantCode: http.StatusOK,
+			wantResp: "<html></html>",
+			wantHeaders: map[string]string{
+				"Content-Type":  "text/html; charset=utf-8",
+				"ETag":          etag("index.html"),
+				"Last-Modified": "Tue, 01 Nov 2022 10:00:00 GMT",
+				"Accept-Ranges": "bytes",
+			},
+		},
+		{
+			name:     "nested",
+			path:     "/static/js/app.js",
+			wantCode: http.StatusOK,
+			wantResp: "console.log(1)",
+			wantHeaders: map[string]string{
+				"Content-Type": "application/javascript",
+			},
+		},
+		{
+			name:     "detect content type",
+			path:     "/static/file.unknown1",
+			wantCode: http.StatusOK,
+			wantResp: "hello",
+			wantHeaders: map[string]string{
+				"Content-Type": "text/plain; charset=utf-8",
+			},
+		},
+		{
+			name:     "not found",
+			path:     "/static/missing.js",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "dir",
+			path:     "/static/js",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "traversal",
+			path:     "/static/../../index.html",
+			wantCode: http.StatusOK,
+			wantResp: "<html></html>",
+		},
+		{
+			name:     "if none match",
+			path:     "/static/index.html",
+			headers:  map[string]string{"If-None-Match": etag("index.html")},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "if none match weak",
+			path:     "/static/index.html",
+			headers:  map[string]string{"If-None-Match": `"abc", W/` + etag("index.html")},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name: "if none match mismatch",
+			path: "/static/index.html",
+			headers: map[string]string{
+				"If-None-Match":     `"abc"`,
+				"If-Modified-Since": "Tue, 01 Nov 2022 10:00:00 GMT",
+			},
+			wantCode: http.StatusOK,
+			wantResp: "<html></html>",
+		},
+		{
+			name:     "if modified since",
+			path:     "/static/index.html",
+			headers:  map[string]string{"If-Modified-Since": "Tue, 01 Nov 2022 10:00:00 GMT"},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "modified",
+			path:     "/static/index.html",
+			headers:  map[string]string{"If-Modified-Since": "Tue, 01 Nov 2022 09:59:59 GMT"},
+			wantCode: http.StatusOK,
+			wantResp: "<html></html>",
+		},
+		{
+			name:     "range",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=2-5"},
+			wantCode: http.StatusPartialContent,
+			wantResp: "2345",
+			wantHeaders: map[string]string{
+				"Content-Range": "bytes 2-5/10",
+			},
+		},
+		{
+			name:     "range open end",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=7-"},
+			wantCode: http.StatusPartialContent,
+			wantResp: "789",
+			wantHeaders: map[string]string{
+				"Content-Range": "bytes 7-9/10",
+			},
+		},
+		{
+			name:     "range suffix",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=-3"},
+			wantCode: http.StatusPartialContent,
+			wantResp: "789",
+		},
+		{
+			name:     "range end overflow",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=8-100"},
+			wantCode: http.StatusPartialContent,
+			wantResp: "89",
+		},
+		{
+			name:     "range not satisfiable",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=10-"},
+			wantCode: http.StatusRequestedRangeNotSatisfiable,
+			wantHeaders: map[string]string{
+				"Content-Range": "bytes */10",
+			},
+		},
+		{
+			name:     "multi range",
+			path:     "/static/data.txt",
+			headers:  map[string]string{"Range": "bytes=0-1,3-4"},
+			wantCode: http.StatusOK,
+			wantResp: "0123456789",
+		},
+		{
+			name: "if range",
+			path: "/static/data.txt",
+			headers: map[string]string{
+				"Range":    "bytes=0-1",
+				"If-Range": etag("data.txt"),
+			},
+			wantCode: http.StatusPartialContent,
+			wantResp: "01",
+		},
+		{
+			name: "if range mismatch",
+			path: "/static/data.txt",
+			headers: map[string]string{
+				"Range":    "bytes=0-1",
+				"If-Range": `"abc"`,
+			},
+			wantCode: http.StatusOK,
+			wantResp: "0123456789",
+		},
+		{
+			name: "if range date",
+			path: "/static/data.txt",
+			headers: map[string]string{
+				"Range":    "bytes=0-1",
+				"If-Range": "Tue, 01 Nov 2022 10:00:00 GMT",
+			},
+			wantCode: http.StatusPartialContent,
+			wantResp: "01",
+		},
+		{
+			name: "if range date mismatch",
+			path: "/static/data.txt",
+			headers: map[string]string{
+				"Range":    "bytes=0-1",
+				"If-Range": "Tue, 01 Nov 2022 09:00:00 GMT",
+			},
+			wantCode: http.StatusOK,
+			wantResp: "0123456789",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, recorder.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestStaticResourceHandler_Dir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.wasm"), []byte("wasm"), 0644))
+	h := NewStaticResourceHandler(dir,
+		StaticWithPathParam("name"),
+		StaticWithExtension(map[string]string{".wasm": "application/wasm"}))
+	s := NewHTTPServer()
+	s.Get("/assets/*name", h.Handle)
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/assets/hello.wasm", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "wasm", recorder.Body.String())
+	assert.Equal(t, "application/wasm", recorder.Header().Get("Content-Type"))
+	assert.NotEmpty(t, recorder.Header().Get("Last-Modified"))
+}
+
+func TestStaticResourceHandler_Cache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":   {Data: []byte("a")},
+		"b.txt":   {Data: []byte("b")},
+		"c.txt":   {Data: []byte("c")},
+		"big.txt": {Data: []byte("big file")},
+	}
+	h := NewStaticResourceHandlerFS(fsys, StaticWithCache(4, 2))
+	s := NewHTTPServer()
+	s.Get("/*filepath", h.Handle)
+	get := func(path string) string {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Body.String()
+	}
+
+	assert.Equal(t, "a", get("/a.txt"))
+	assert.Equal(t, "b", get("/b.txt"))
+	assert.Equal(t, "big file", get("/big.txt"))
+	// 修改时间和大小都没有变，仍然返回缓存的内容
+	fsys["a.txt"].Data = []byte("A")
+	fsys["b.txt"].Data = []byte("B")
+	fsys["big.txt"].Data = []byte("big file2")
+	assert.Equal(t, "a", get("/a.txt"))
+	// 太大的文件不会被缓存
+	assert.Equal(t, "big file2", get("/big.txt"))
+	assert.Nil(t, h.cache.items["big.txt"])
+
+	// 缓存了 a 和 b，访问 c 会淘汰最久没有访问的 b
+	assert.Equal(t, "c", get("/c.txt"))
+	assert.Equal(t, "a", get("/a.txt"))
+	assert.Equal(t, "B", get("/b.txt"))
+
+	// 文件被修改之后缓存失效
+	fsys["a.txt"].ModTime = time.Now()
+	assert.Equal(t, "A", get("/a.txt"))
+	fsys["c.txt"].Data = []byte("cc")
+	assert.Equal(t, "cc", get("/c.txt"))
+	assert.Equal(t, 2, h.cache.lst.Len())
+	assert.Equal(t, 2, len(h.cache.items))
+}
+
+func TestStaticResourceHandler_Stream(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+	fsys := fstest.MapFS{
+		// 和 embed.FS 一样没有修改时间
+		"big.unknown1": {Data: []byte(data)},
+	}
+	h := NewStaticResourceHandlerFS(fsys, StaticWithCache(10, 10))
+	s := NewHTTPServer()
+	var status int
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = ctx.RespStatusCode
+		}
+	})
+	s.Get("/*filepath", h.Handle)
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/big.unknown1", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	// 直接写响应的时候 middleware 也能拿到响应码
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, data, recorder.Body.String())
+	assert.Equal(t, "1000", recorder.Header().Get("Content-Length"))
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	etag := recorder.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	// 大文件不会被缓存
+	assert.Equal(t, 0, h.cache.lst.Len())
+
+	req := httptest.NewRequest(http.MethodGet, "/big.unknown1", nil)
+	req.Header.Set("Range", "bytes=995-")
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusPartialContent, recorder.Code)
+	assert.Equal(t, http.StatusPartialContent, status)
+	assert.Equal(t, "56789", recorder.Body.String())
+	assert.Equal(t, "bytes 995-999/1000", recorder.Header().Get("Content-Range"))
+
+	req = httptest.NewRequest(http.MethodGet, "/big.unknown1", nil)
+	req.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+}
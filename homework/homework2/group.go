@@ -0,0 +1,148 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RouteGroup 路由分组
+// 同一个分组内的路由共享同一个前缀和同一组 middleware
+// 分组本身并不持有路由树，所有的路由最终都注册到 HTTPServer 的 router 上
+type RouteGroup struct {
+	s      *HTTPServer
+	prefix string
+	mdls   []Middleware
+	// used 分组已经注册了路由或者创建了子分组，
+	// 这些路由和子分组都复制了一份 mdls，所以不能再调用 Use
+	used bool
+}
+
+// Group 创建一个路由分组
+// prefix 必须以 / 开头，并且不能以 / 结尾，例如 /api/v1
+// mdls 只会作用在该分组（包括子分组）的路由上
+func (s *HTTPServer) Group(prefix string, mdls ...Middleware) *RouteGroup {
+	checkGroupPrefix(prefix)
+	return &RouteGroup{
+		s:      s,
+		prefix: prefix,
+		mdls:   mdls,
+	}
+}
+
+// Group 创建子分组
+// 子分组的前缀是父分组的前缀加上 prefix，
+// 子分组会继承父分组的 middleware，并且父分组的 middleware 先执行
+func (g *RouteGroup) Group(prefix string, mdls ...Middleware) *RouteGroup {
+	checkGroupPrefix(prefix)
+	g.used = true
+	// 必须复制一份，避免子分组之间互相影响
+	res := make([]Middleware, 0, len(g.mdls)+len(mdls))
+	res = append(res, g.mdls...)
+	res = append(res, mdls...)
+	return &RouteGroup{
+		s:      g.s,
+		prefix: g.joinPath(prefix),
+		mdls:   res,
+	}
+}
+
+// Use 为分组添加 middleware
+// 必须在注册路由和创建子分组之前调用，否则会 panic
+func (g *RouteGroup) Use(mdls ...Middleware) *RouteGroup {
+	if g.used {
+		panic(fmt.Sprintf("web: 分组 [%s] 已经注册了路由或者子分组，不能再调用 Use", g.prefix))
+	}
+	g.mdls = append(g.mdls, mdls...)
+	return g
+}
+
+// Name 给最近一次注册的路由命名
+func (g *RouteGroup) Name(name string) *RouteGroup {
+	g.s.Name(name)
+	return g
+}
+
+// Handle 在分组上注册任意 HTTP 方法的路由
+func (g *RouteGroup) Handle(method string, path string, handler HandleFunc) *RouteGroup {
+	g.addRoute(method, path, handler)
+	return g
+}
+
+func (g *RouteGroup) Get(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodGet, path, handler)
+}
+
+func (g *RouteGroup) Post(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodPost, path, handler)
+}
+
+func (g *RouteGroup) Put(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodPut, path, handler)
+}
+
+func (g *RouteGroup) Delete(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodDelete, path, handler)
+}
+
+func (g *RouteGroup) Patch(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodPatch, path, handler)
+}
+
+func (g *RouteGroup) Head(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodHead, path, handler)
+}
+
+func (g *RouteGroup) Options(path string, handler HandleFunc) *RouteGroup {
+	return g.Handle(http.MethodOptions, path, handler)
+}
+
+// Any 在所有的标准 HTTP 方法上注册同一个路由，参考 HTTPServer.Any
+func (g *RouteGroup) Any(path string, handler HandleFunc) *RouteGroup {
+	routes := make([]namedRoute, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		g.Handle(method, path, handler)
+		routes = append(routes, g.s.lastRoutes...)
+	}
+	g.s.lastRoutes = routes
+	return g
+}
+
+// HandleWith 在分组上注册带有请求头约束的路由，参考 HTTPServer.HandleWith
+func (g *RouteGroup) HandleWith(method string, path string, handler HandleFunc, headers ...HeaderMatcher) *RouteGroup {
+	g.addRoute(method, path, handler, headers...)
+	return g
+}
+
+func (g *RouteGroup) addRoute(method string, path string, handler HandleFunc, headers ...HeaderMatcher) {
+	if path == "" || path[0] != '/' {
+		panic("web: 路由必须以 / 开头")
+	}
+	// 分组的 middleware 只作用在这个 handler 上，所以和 handler 保存在一起，
+	// 而不是像 UseV1 那样注册在节点上，否则会作用在其它能够匹配的路由上
+	g.used = true
+	mdls := append([]Middleware(nil), g.mdls...)
+	if err := g.s.addVariantE(method, g.joinPath(path), handler, headers, mdls); err != nil {
+		panic(err.Error())
+	}
+}
+
+// joinPath 拼接分组前缀和路由
+// 在分组上注册 / 相当于注册分组前缀本身
+func (g *RouteGroup) joinPath(path string) string {
+	if path == "/" {
+		return g.prefix
+	}
+	if g.prefix == "/" {
+		return path
+	}
+	return g.prefix + path
+}
+
+func checkGroupPrefix(prefix string) {
+	if prefix == "" || prefix[0] != '/' {
+		panic(fmt.Sprintf("web: 分组前缀必须以 / 开头 [%s]", prefix))
+	}
+	if prefix != "/" && prefix[len(prefix)-1] == '/' {
+		panic(fmt.Sprintf("web: 分组前缀不能以 / 结尾 [%s]", prefix))
+	}
+}
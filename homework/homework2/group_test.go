@@ -0,0 +1,165 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteGroup(t *testing.T) {
+	var mdlBuilder = func(i byte) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				ctx.RespData = append(ctx.RespData, i)
+				next(ctx)
+			}
+		}
+	}
+	var handler = func(ctx *Context) {
+		ctx.RespData = append(ctx.RespData, '!')
+	}
+
+	s := NewHTTPServer()
+	s.Get("/api/v1/ping", handler)
+	v1 := s.Group("/api/v1", mdlBuilder('v'))
+	v1.Get("/", handler)
+	user := v1.Group("/user", mdlBuilder('u'))
+	user.Get("/:id", func(ctx *Context) {
+		ctx.RespData = append(ctx.RespData, ctx.PathParams["id"]...)
+	})
+	order := v1.Group("/order")
+	order.Use(mdlBuilder('o'))
+	order.Post("/create", handler)
+	root := s.Group("/")
+	root.Get("/login", handler)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantResp string
+	}{
+		{
+			// 不是通过分组注册的，所以不会执行分组的 middleware
+			name:     "not in group",
+			method:   http.MethodGet,
+			path:     "/api/v1/ping",
+			wantCode: http.StatusOK,
+			wantResp: "!",
+		},
+		{
+			name:     "group root",
+			method:   http.MethodGet,
+			path:     "/api/v1",
+			wantCode: http.StatusOK,
+			wantResp: "v!",
+		},
+		{
+			name:     "nested group",
+			method:   http.MethodGet,
+			path:     "/api/v1/user/123",
+			wantCode: http.StatusOK,
+			wantResp: "vu123",
+		},
+		{
+			name:     "group use",
+			method:   http.MethodPost,
+			path:     "/api/v1/order/create",
+			wantCode: http.StatusOK,
+			wantResp: "vo!",
+		},
+		{
+			name:     "root group",
+			method:   http.MethodGet,
+			path:     "/login",
+			wantCode: http.StatusOK,
+			wantResp: "!",
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodGet,
+			path:     "/api/v1/order/create",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/api/v1/order",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+		})
+	}
+}
+
+func TestRouteGroup_Prefix(t *testing.T) {
+	s := NewHTTPServer()
+	assert.PanicsWithValue(t, "web: 分组前缀必须以 / 开头 [api]", func() {
+		s.Group("api")
+	})
+	assert.PanicsWithValue(t, "web: 分组前缀不能以 / 结尾 [/api/]", func() {
+		s.Group("/api/")
+	})
+	assert.PanicsWithValue(t, "web: 路由必须以 / 开头", func() {
+		s.Group("/api").Get("user", func(ctx *Context) {})
+	})
+}
+
+func TestRouteGroup_Mdls(t *testing.T) {
+	var mdlBuilder = func(i byte) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				ctx.RespData = append(ctx.RespData, i)
+				next(ctx)
+			}
+		}
+	}
+	s := NewHTTPServer()
+	g := s.Group("/api", mdlBuilder('g'))
+	g.Get("/user", mockUserHandler)
+	g.Get("/:name", mockOrderHandler)
+	// 分组的 middleware 比路由上的 middleware 更不精确，所以先执行
+	s.UseV1(http.MethodGet, "/api/user", mdlBuilder('r'))
+	// 已经注册的路由不会被后面的 Use 影响，所以直接 panic
+	assert.PanicsWithValue(t, "web: 分组 [/api] 已经注册了路由或者子分组，不能再调用 Use", func() {
+		g.Use(mdlBuilder('x'))
+	})
+	sub := s.Group("/sub")
+	sub.Group("/v1")
+	assert.PanicsWithValue(t, "web: 分组 [/sub] 已经注册了路由或者子分组，不能再调用 Use", func() {
+		sub.Use(mdlBuilder('x'))
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/user", nil))
+	assert.Equal(t, "gr", recorder.Body.String())
+	// 同一个分组里面其它能够匹配的路由不会让分组的 middleware 执行两次
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/order", nil))
+	assert.Equal(t, "g", recorder.Body.String())
+
+	assert.Equal(t, []RouteInfo{
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/api/:name",
+			Handler:     "homework/homework2.mockOrderHandler",
+			Middlewares: 1,
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/api/user",
+			Handler:     "homework/homework2.mockUserHandler",
+			Middlewares: 2,
+		},
+	}, s.Routes())
+}
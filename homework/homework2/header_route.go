@@ -0,0 +1,106 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HeaderMatcher 路由的请求头约束
+type HeaderMatcher struct {
+	Key string
+	// Value 为空的时候，只要求请求头存在
+	Value string
+}
+
+// Header 要求请求头 key 的值是 value
+// value 为空的时候，只要求存在请求头 key
+func Header(key string, value string) HeaderMatcher {
+	return HeaderMatcher{Key: http.CanonicalHeaderKey(key), Value: value}
+}
+
+// Version 要求请求头 Accept-Version 的值是 version
+func Version(version string) HeaderMatcher {
+	return Header("Accept-Version", version)
+}
+
+func (m HeaderMatcher) match(header http.Header) bool {
+	vals := header.Values(m.Key)
+	if len(vals) == 0 {
+		return false
+	}
+	if m.Value == "" {
+		return true
+	}
+	for _, val := range vals {
+		if val == m.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func (m HeaderMatcher) String() string {
+	if m.Value == "" {
+		return m.Key
+	}
+	return m.Key + ": " + m.Value
+}
+
+// routeVariant 带有请求头约束的 HandleFunc
+type routeVariant struct {
+	headers        []HeaderMatcher
+	handler        HandleFunc
+	groupMdls      []Middleware
+	matchedHandler HandleFunc
+}
+
+func (v *routeVariant) match(header http.Header) bool {
+	for _, m := range v.headers {
+		if !m.match(header) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectHandler 根据请求头选择 HandleFunc
+// 在满足约束的里面选择约束最多的，都不满足的话使用没有约束的
+func (n *node) selectHandler(req *http.Request) (HandleFunc, bool) {
+	var res *routeVariant
+	for _, v := range n.variants {
+		if (res == nil || len(v.headers) > len(res.headers)) && v.match(req.Header) {
+			res = v
+		}
+	}
+	if res != nil {
+		return res.matchedHandler, true
+	}
+	return n.matchedHandler, n.matchedHandler != nil
+}
+
+// canonicalHeaders 统一请求头的格式，用户可能直接构造 HeaderMatcher
+func canonicalHeaders(headers []HeaderMatcher) []HeaderMatcher {
+	res := make([]HeaderMatcher, 0, len(headers))
+	for _, h := range headers {
+		res = append(res, Header(h.Key, h.Value))
+	}
+	return res
+}
+
+// sameHeaders 判断两组约束是否相同，不考虑顺序
+func sameHeaders(x []HeaderMatcher, y []HeaderMatcher) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	return strings.Join(headerStrings(x), "\n") == strings.Join(headerStrings(y), "\n")
+}
+
+func headerStrings(headers []HeaderMatcher) []string {
+	res := make([]string, 0, len(headers))
+	for _, h := range headers {
+		res = append(res, h.String())
+	}
+	sort.Strings(res)
+	return res
+}
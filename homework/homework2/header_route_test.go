@@ -0,0 +1,142 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPServer_HandleWith(t *testing.T) {
+	var handlerBuilder = func(resp string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.RespData = []byte(resp)
+		}
+	}
+	s := NewHTTPServer()
+	s.Get("/user", handlerBuilder("default"))
+	s.HandleWith(http.MethodGet, "/user", handlerBuilder("v1"), Version("v1"))
+	s.HandleWith(http.MethodGet, "/user", handlerBuilder("v2"), Version("v2"))
+	s.HandleWith(http.MethodGet, "/user", handlerBuilder("v2 beta"),
+		Version("v2"), HeaderMatcher{Key: "x-beta"})
+	s.HandleWith(http.MethodGet, "/order", handlerBuilder("order v1"), Version("v1"))
+	s.Group("/api").HandleWith(http.MethodPost, "/order", handlerBuilder("api v1"), Version("v1"))
+
+	assert.PanicsWithValue(t, "web: 路由冲突[/user]，请求头约束重复 [Accept-Version: v2 X-Beta]", func() {
+		s.HandleWith(http.MethodGet, "/user", handlerBuilder("v2 beta"),
+			Header("X-Beta", ""), Version("v2"))
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		headers  map[string]string
+		wantCode int
+		wantResp string
+	}{
+		{
+			name:     "no header",
+			method:   http.MethodGet,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantResp: "default",
+		},
+		{
+			name:     "v1",
+			method:   http.MethodGet,
+			path:     "/user",
+			headers:  map[string]string{"Accept-Version": "v1"},
+			wantCode: http.StatusOK,
+			wantResp: "v1",
+		},
+		{
+			name:     "v2",
+			method:   http.MethodGet,
+			path:     "/user",
+			headers:  map[string]string{"Accept-Version": "v2"},
+			wantCode: http.StatusOK,
+			wantResp: "v2",
+		},
+		{
+			name:     "most specific",
+			method:   http.MethodGet,
+			path:     "/user",
+			headers:  map[string]string{"Accept-Version": "v2", "X-Beta": "true"},
+			wantCode: http.StatusOK,
+			wantResp: "v2 beta",
+		},
+		{
+			name:     "fallback",
+			method:   http.MethodGet,
+			path:     "/user",
+			headers:  map[string]string{"Accept-Version": "v3"},
+			wantCode: http.StatusOK,
+			wantResp: "default",
+		},
+		{
+			name:     "not acceptable",
+			method:   http.MethodGet,
+			path:     "/order",
+			headers:  map[string]string{"Accept-Version": "v2"},
+			wantCode: http.StatusNotAcceptable,
+		},
+		{
+			name:     "group",
+			method:   http.MethodPost,
+			path:     "/api/order",
+			headers:  map[string]string{"Accept-Version": "v1"},
+			wantCode: http.StatusOK,
+			wantResp: "api v1",
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			path:     "/order",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+		})
+	}
+
+	routes := s.Routes()
+	assert.Equal(t, 6, len(routes))
+	assert.Equal(t, []string{"Accept-Version: v2", "X-Beta"}, routes[4].Headers)
+}
+
+func TestHTTPServer_AddRoutes_Variants(t *testing.T) {
+	s := NewHTTPServer()
+	s.HandleWith(http.MethodGet, "/user", func(ctx *Context) {
+		ctx.RespData = append(ctx.RespData, '!')
+	}, Version("v2"))
+	s.UseV1(http.MethodGet, "/user", func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.RespData = append(ctx.RespData, 'm')
+			next(ctx)
+		}
+	})
+	// 注册失败不能影响已有路由上的 middleware
+	err := s.AddRoutes(
+		RouteDef{Method: http.MethodGet, Path: "/user", Handler: func(ctx *Context) {},
+			Headers: []HeaderMatcher{Version("v3")}},
+		RouteDef{Method: http.MethodGet, Path: "order", Handler: func(ctx *Context) {}},
+	)
+	assert.Error(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Accept-Version", "v2")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, "m!", recorder.Body.String())
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Host 返回处理某个域名的 HTTPServer，不存在的话会创建一个
+// pattern 可以是完整的域名 api.example.com，也可以是 *.example.com 这种泛域名，
+// 泛域名能够匹配任意层级的子域名，但是不能匹配 example.com 本身。
+// 每个域名都有自己的路由树和 middleware，和当前 HTTPServer 的互不影响。
+// 请求会先按照 Host 找到对应的 HTTPServer，
+// 优先完全匹配，其次是后缀最长的泛域名，都没有的话由当前 HTTPServer 处理。
+// Host 和路由一样，必须在启动之前调用
+func (s *HTTPServer) Host(pattern string) *HTTPServer {
+	pattern = strings.ToLower(pattern)
+	if pattern == "" || strings.ContainsAny(pattern, "/:") {
+		panic(fmt.Sprintf("web: 非法域名 [%s]", pattern))
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:]
+		for _, h := range s.hosts.wildcards {
+			if h.suffix == suffix {
+				return h.server
+			}
+		}
+		res := s.newHostServer()
+		s.hosts.wildcards = append(s.hosts.wildcards, wildcardHost{suffix: suffix, server: res})
+		// 后缀越长越精确，越先匹配
+		sort.SliceStable(s.hosts.wildcards, func(i, j int) bool {
+			return len(s.hosts.wildcards[i].suffix) > len(s.hosts.wildcards[j].suffix)
+		})
+		return res
+	}
+	if strings.Contains(pattern, "*") {
+		panic(fmt.Sprintf("web: 非法域名，只支持 *.example.com 形式的泛域名 [%s]", pattern))
+	}
+	if s.hosts.exact == nil {
+		s.hosts.exact = make(map[string]*HTTPServer, 4)
+	}
+	res, ok := s.hosts.exact[pattern]
+	if !ok {
+		res = s.newHostServer()
+		s.hosts.exact[pattern] = res
+	}
+	return res
+}
+
+// newHostServer 创建域名的 HTTPServer，使用和当前 HTTPServer 一样的配置
+// WebSocket 连接也记录在当前 HTTPServer 上，这样退出的时候能够一起关闭
+func (s *HTTPServer) newHostServer() *HTTPServer {
+	res := NewHTTPServer(ServerWithTemplateEngine(s.tplEngine))
+	res.wsConns = s.wsConns
+	return res
+}
+
+// hosts 按照域名组织的 HTTPServer
+type hosts struct {
+	exact map[string]*HTTPServer
+	// 按照后缀长度从长到短排列
+	wildcards []wildcardHost
+}
+
+type wildcardHost struct {
+	// suffix 形如 .example.com
+	suffix string
+	server *HTTPServer
+}
+
+// find 根据请求的 Host 找到对应的 HTTPServer，找不到的时候返回 nil
+func (h *hosts) find(host string) *HTTPServer {
+	if h.exact == nil && h.wildcards == nil {
+		return nil
+	}
+	// Host 里面可能带有端口
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+	if res, ok := h.exact[host]; ok {
+		return res
+	}
+	for _, wh := range h.wildcards {
+		if len(host) > len(wh.suffix) && strings.HasSuffix(host, wh.suffix) {
+			return wh.server
+		}
+	}
+	return nil
+}
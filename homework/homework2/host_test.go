@@ -0,0 +1,114 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPServer_Host(t *testing.T) {
+	var handlerBuilder = func(resp string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.RespData = append(ctx.RespData, resp...)
+		}
+	}
+	var mdlBuilder = func(resp string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				ctx.RespData = append(ctx.RespData, resp...)
+				next(ctx)
+			}
+		}
+	}
+
+	s := NewHTTPServer()
+	s.Use(mdlBuilder("default-"))
+	s.Get("/", handlerBuilder("home"))
+
+	api := s.Host("api.example.com")
+	api.Use(mdlBuilder("api-"))
+	api.Get("/user", handlerBuilder("user"))
+	// 同一个域名返回同一个 HTTPServer
+	s.Host("API.example.com").Get("/order", handlerBuilder("order"))
+
+	s.Host("*.example.com").Get("/", handlerBuilder("tenant"))
+	s.Host("*.admin.example.com").Get("/", handlerBuilder("admin"))
+
+	assert.PanicsWithValue(t, "web: 非法域名，只支持 *.example.com 形式的泛域名 [a.*.com]", func() {
+		s.Host("a.*.com")
+	})
+	assert.PanicsWithValue(t, "web: 非法域名 []", func() {
+		s.Host("")
+	})
+
+	testCases := []struct {
+		name     string
+		host     string
+		path     string
+		wantCode int
+		wantResp string
+	}{
+		{
+			name:     "exact",
+			host:     "api.example.com",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantResp: "api-user",
+		},
+		{
+			name:     "exact with port",
+			host:     "Api.Example.com:8080",
+			path:     "/order",
+			wantCode: http.StatusOK,
+			wantResp: "api-order",
+		},
+		{
+			// 命中了域名之后，不会再回退到默认的 HTTPServer
+			name:     "exact not found",
+			host:     "api.example.com",
+			path:     "/",
+			wantCode: http.StatusNotFound,
+			wantResp: "api-",
+		},
+		{
+			name:     "wildcard",
+			host:     "foo.example.com",
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantResp: "tenant",
+		},
+		{
+			name:     "longest wildcard",
+			host:     "foo.admin.example.com",
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantResp: "admin",
+		},
+		{
+			name:     "wildcard not match root domain",
+			host:     "example.com",
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantResp: "default-home",
+		},
+		{
+			name:     "default",
+			host:     "localhost:8081",
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantResp: "default-home",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Limiter 限流算法
+type Limiter interface {
+	// Allow 判断 key 对应的请求是否可以通过
+	Allow(key string) Result
+}
+
+// Result 限流的结果，用于设置 RateLimit-* 和 Retry-After 响应头
+type Result struct {
+	Allowed bool
+	// Limit 额度的上限
+	Limit int
+	// Remaining 剩余的额度
+	Remaining int
+	// Reset 额度完全恢复需要的时间
+	Reset time.Duration
+	// RetryAfter 被限流的时候，至少需要等待多久才能重试
+	RetryAfter time.Duration
+}
+
+type Option func(s *store)
+
+// WithMaxKeys 最多保存多少个 key 的状态，默认是 10000
+// 超过之后会淘汰最久没有访问的 key，避免攻击者使用大量的 key 耗尽内存
+func WithMaxKeys(maxKeys int) Option {
+	return func(s *store) {
+		s.maxKeys = maxKeys
+	}
+}
+
+// store 保存每个 key 的限流状态，按照访问顺序排列的 LRU
+// 除了超过容量的时候淘汰，空闲超过 ttl 的 key 也会被淘汰，
+// 因为这时候它的额度已经完全恢复了，和新建的没有区别
+type store struct {
+	mutex   sync.Mutex
+	maxKeys int
+	ttl     time.Duration
+	lst     *list.List
+	items   map[string]*list.Element
+	now     func() time.Time
+}
+
+type entry struct {
+	key        string
+	lastAccess time.Time
+	state      any
+}
+
+func newStore(ttl time.Duration, opts []Option) *store {
+	res := &store{
+		maxKeys: 10000,
+		ttl:     ttl,
+		lst:     list.New(),
+		items:   make(map[string]*list.Element, 64),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// do 在锁里面执行 fn，state 不存在的时候使用 newState 创建
+func (s *store) do(key string, newState func(now time.Time) any, fn func(state any, now time.Time) Result) Result {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.now()
+	s.evictExpired(now)
+	ele, ok := s.items[key]
+	if ok {
+		s.lst.MoveToFront(ele)
+	} else {
+		ele = s.lst.PushFront(&entry{key: key, state: newState(now)})
+		s.items[key] = ele
+		for s.lst.Len() > s.maxKeys {
+			s.remove(s.lst.Back())
+		}
+	}
+	e := ele.Value.(*entry)
+	e.lastAccess = now
+	return fn(e.state, now)
+}
+
+// evictExpired 越往后越久没有访问，所以只需要从后往前检查
+func (s *store) evictExpired(now time.Time) {
+	for ele := s.lst.Back(); ele != nil; ele = s.lst.Back() {
+		if now.Sub(ele.Value.(*entry).lastAccess) < s.ttl {
+			return
+		}
+		s.remove(ele)
+	}
+}
+
+func (s *store) remove(ele *list.Element) {
+	s.lst.Remove(ele)
+	delete(s.items, ele.Value.(*entry).key)
+}
+
+func (s *store) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lst.Len()
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l := NewTokenBucketLimiter(3, time.Second)
+	l.store.now = clock.Now
+
+	// 一开始桶是满的，允许突发
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}, l.Allow("a"))
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}, l.Allow("a"))
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}, l.Allow("a"))
+	assert.Equal(t, Result{Limit: 3, Reset: 3 * time.Second, RetryAfter: time.Second}, l.Allow("a"))
+	// 不同的 key 互不影响
+	assert.True(t, l.Allow("b").Allowed)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.Equal(t, Result{Limit: 3, Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}, l.Allow("a"))
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}, l.Allow("a"))
+
+	// 桶满了之后不会继续放入令牌
+	clock.now = clock.now.Add(time.Hour)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}, l.Allow("a"))
+
+	assert.PanicsWithValue(t, "web: 令牌桶的容量和放入令牌的间隔必须大于 0", func() {
+		NewTokenBucketLimiter(0, time.Second)
+	})
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l := NewSlidingWindowLimiter(2, 10*time.Second)
+	l.store.now = clock.Now
+
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 10 * time.Second}, l.Allow("a"))
+	clock.now = clock.now.Add(4 * time.Second)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}, l.Allow("a"))
+	clock.now = clock.now.Add(4 * time.Second)
+	assert.Equal(t, Result{Limit: 2, Reset: 6 * time.Second, RetryAfter: 2 * time.Second}, l.Allow("a"))
+
+	// 第一个请求滑出了窗口，但是第二个还在
+	clock.now = clock.now.Add(2 * time.Second)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}, l.Allow("a"))
+	assert.Equal(t, Result{Limit: 2, Reset: 10 * time.Second, RetryAfter: 4 * time.Second}, l.Allow("a"))
+
+	clock.now = clock.now.Add(10 * time.Second)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 10 * time.Second}, l.Allow("a"))
+}
+
+func TestStore_Evict(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l := NewSlidingWindowLimiter(1, time.Minute, WithMaxKeys(3))
+	l.store.now = clock.Now
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, l.Allow(strconv.Itoa(i)).Allowed)
+	}
+	// 超过容量，最久没有访问的 0 和 1 被淘汰了，所以又可以通过了
+	assert.Equal(t, 3, l.store.len())
+	assert.False(t, l.Allow("4").Allowed)
+	assert.True(t, l.Allow("0").Allowed)
+
+	// 空闲超过窗口大小的 key 被淘汰了
+	clock.now = clock.now.Add(time.Minute)
+	assert.True(t, l.Allow("5").Allowed)
+	assert.Equal(t, 1, l.store.len())
+}
+
+func TestSlidingWindowLimiter_Grow(t *testing.T) {
+	l := NewSlidingWindowLimiter(1000, 30*time.Second)
+	allow := func(sec int64) Result {
+		l.store.now = func() time.Time {
+			return time.Unix(sec, 0)
+		}
+		return l.Allow("a")
+	}
+
+	assert.True(t, allow(0).Allowed)
+	// 不会预先分配 limit 个
+	ts := l.store.items["a"].Value.(*entry).state.(*timestamps)
+	assert.Equal(t, 4, len(ts.items))
+
+	allow(10)
+	allow(20)
+	// 0 滑出了窗口，之后的请求绕回到切片的开头
+	allow(30)
+	allow(31)
+	assert.Equal(t, 4, len(ts.items))
+	// 扩容之后的顺序仍然是 10, 20, 30, 31, 32
+	assert.Equal(t, 1000-5, allow(32).Remaining)
+	assert.Equal(t, 8, len(ts.items))
+	assert.Equal(t, 1000-5, allow(40).Remaining)
+	assert.Equal(t, Result{Allowed: true, Limit: 1000, Remaining: 1000 - 3, Reset: 30 * time.Second}, allow(61))
+}
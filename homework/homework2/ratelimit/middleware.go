@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	web "homework/homework2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type MiddlewareBuilder struct {
+	limiter Limiter
+	keyFunc func(ctx *web.Context) string
+	// limitedHandler 被限流的时候调用，用于设置响应
+	limitedHandler web.HandleFunc
+}
+
+// NewBuilder 默认按照客户端 IP 限流，被限流的时候返回 429
+func NewBuilder(limiter Limiter) *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		limiter: limiter,
+		keyFunc: KeyByIP,
+		limitedHandler: func(ctx *web.Context) {
+			ctx.RespStatusCode = http.StatusTooManyRequests
+			ctx.RespData = []byte("请求太频繁，请稍后再试")
+		},
+	}
+}
+
+// KeyFunc 设置限流的 key，返回空字符串的请求不会被限流
+func (b *MiddlewareBuilder) KeyFunc(fn func(ctx *web.Context) string) *MiddlewareBuilder {
+	b.keyFunc = fn
+	return b
+}
+
+// LimitedHandler 设置被限流的时候的响应，
+// 调用之前已经设置好了 RateLimit-* 和 Retry-After 响应头
+func (b *MiddlewareBuilder) LimitedHandler(fn web.HandleFunc) *MiddlewareBuilder {
+	b.limitedHandler = fn
+	return b
+}
+
+func (b *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			key := b.keyFunc(ctx)
+			if key == "" {
+				next(ctx)
+				return
+			}
+			res := b.limiter.Allow(key)
+			header := ctx.Resp.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", seconds(res.Reset))
+			if !res.Allowed {
+				header.Set("Retry-After", seconds(res.RetryAfter))
+				b.limitedHandler(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// seconds 响应头里面的时间都是秒，向上取整，避免客户端过早重试
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// KeyByIP 按照客户端 IP 限流，使用的是 RemoteAddr
+// 如果部署在反向代理后面，需要使用 KeyFunc 从代理设置的请求头里面取 IP
+func KeyByIP(ctx *web.Context) string {
+	host, _, err := net.SplitHostPort(ctx.Req.RemoteAddr)
+	if err != nil {
+		return ctx.Req.RemoteAddr
+	}
+	return host
+}
+
+// KeyByRoute 按照路由限流，所有客户端共享额度
+// MatchedRoute 是在路由匹配之后才设置的，所以需要使用 UseV1 或者 Group 注册在路由上，例如：
+//
+//	s.UseV1(http.MethodPost, "/login", ratelimit.NewBuilder(limiter).KeyFunc(ratelimit.KeyByRoute).Build())
+func KeyByRoute(ctx *web.Context) string {
+	if ctx.MatchedRoute == "" {
+		return ""
+	}
+	return ctx.Req.Method + " " + ctx.MatchedRoute
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	web "homework/homework2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	s := web.NewHTTPServer()
+	s.Use(NewBuilder(NewSlidingWindowLimiter(2, time.Minute)).Build())
+	s.Get("/user", func(ctx *web.Context) {
+		ctx.RespData = []byte("hello")
+	})
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		recorder := serve("10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "hello", recorder.Body.String())
+		assert.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, recorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", recorder.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, recorder.Header().Get("Retry-After"))
+	}
+
+	// 同一个 IP 换了端口也一样被限流
+	recorder := serve("10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "请求太频繁，请稍后再试", recorder.Body.String())
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve("10.0.0.2:1234").Code)
+}
+
+func TestMiddlewareBuilder_KeyByRoute(t *testing.T) {
+	s := web.NewHTTPServer()
+	s.Post("/login", func(ctx *web.Context) {
+		ctx.RespData = []byte("ok")
+	})
+	s.Get("/login", func(ctx *web.Context) {
+		ctx.RespData = []byte("page")
+	})
+	s.Get("/user/:id", func(ctx *web.Context) {
+		ctx.RespData = []byte("user")
+	})
+	builder := NewBuilder(NewTokenBucketLimiter(1, time.Minute)).
+		KeyFunc(KeyByRoute).
+		LimitedHandler(func(ctx *web.Context) {
+			ctx.RespStatusCode = http.StatusServiceUnavailable
+			ctx.RespData = []byte(`{"code":10001}`)
+		})
+	s.UseV1(http.MethodPost, "/login", builder.Build())
+	s.UseV1(http.MethodGet, "/user/:id", builder.Build())
+
+	testCases := []struct {
+		method string
+		path   string
+
+		wantCode int
+		wantBody string
+	}{
+		{method: http.MethodPost, path: "/login", wantCode: http.StatusOK, wantBody: "ok"},
+		{method: http.MethodPost, path: "/login", wantCode: http.StatusServiceUnavailable, wantBody: `{"code":10001}`},
+		// 没有注册限流的路由
+		{method: http.MethodGet, path: "/login", wantCode: http.StatusOK, wantBody: "page"},
+		// 同一个路由的不同路径共享额度
+		{method: http.MethodGet, path: "/user/1", wantCode: http.StatusOK, wantBody: "user"},
+		{method: http.MethodGet, path: "/user/2", wantCode: http.StatusServiceUnavailable, wantBody: `{"code":10001}`},
+	}
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.wantCode, recorder.Code, tc.path)
+		assert.Equal(t, tc.wantBody, recorder.Body.String(), tc.path)
+	}
+
+	// 全局的 middleware 里面还没有匹配路由，不会被限流
+	ctx := &web.Context{Req: httptest.NewRequest(http.MethodGet, "/user/1", nil)}
+	assert.Equal(t, "", KeyByRoute(ctx))
+}
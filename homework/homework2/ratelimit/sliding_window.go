@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// SlidingWindowLimiter 滑动窗口，任意 window 时间内最多通过 limit 个请求
+// 和固定窗口相比，不会在窗口的交界处放过两倍的请求
+type SlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	store  *store
+}
+
+// timestamps 窗口内通过的请求的时间，是一个环形队列，按照时间顺序排列
+// items 按需增长，最多 limit 个，避免大量的 key 每个都预先分配 limit 个
+type timestamps struct {
+	items []time.Time
+	head  int
+	size  int
+}
+
+func NewSlidingWindowLimiter(limit int, window time.Duration, opts ...Option) *SlidingWindowLimiter {
+	if limit <= 0 || window <= 0 {
+		panic("web: 滑动窗口的请求数量和窗口大小必须大于 0")
+	}
+	return &SlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		store:  newStore(window, opts),
+	}
+}
+
+func (l *SlidingWindowLimiter) Allow(key string) Result {
+	return l.store.do(key, func(now time.Time) any {
+		return &timestamps{}
+	}, func(state any, now time.Time) Result {
+		ts := state.(*timestamps)
+		// 移除已经滑出窗口的请求
+		for ts.size > 0 && now.Sub(ts.items[ts.head]) >= l.window {
+			ts.head = (ts.head + 1) % len(ts.items)
+			ts.size--
+		}
+
+		res := Result{Limit: l.limit}
+		if ts.size < l.limit {
+			ts.push(now, l.limit)
+			res.Allowed = true
+		} else {
+			// 最早的请求滑出窗口之后，就可以重试了
+			res.RetryAfter = ts.items[ts.head].Add(l.window).Sub(now)
+		}
+		res.Remaining = l.limit - ts.size
+		// 最新的请求滑出窗口之后，额度就完全恢复了
+		if ts.size > 0 {
+			newest := ts.items[(ts.head+ts.size-1)%len(ts.items)]
+			res.Reset = newest.Add(l.window).Sub(now)
+		}
+		return res
+	})
+}
+
+func (ts *timestamps) push(t time.Time, limit int) {
+	if ts.size < len(ts.items) {
+		ts.items[(ts.head+ts.size)%len(ts.items)] = t
+		ts.size++
+		return
+	}
+	// 满了，按照时间顺序复制到两倍大的切片里面，但是不超过 limit
+	capacity := 2 * len(ts.items)
+	if capacity < 4 {
+		capacity = 4
+	}
+	if capacity > limit {
+		capacity = limit
+	}
+	items := make([]time.Time, capacity)
+	n := copy(items, ts.items[ts.head:])
+	copy(items[n:], ts.items[:ts.head])
+	items[ts.size] = t
+	ts.items = items
+	ts.head = 0
+	ts.size++
+}
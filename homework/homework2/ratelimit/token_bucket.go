@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// TokenBucketLimiter 令牌桶，允许一定程度的突发流量
+// 桶里最多有 capacity 个令牌，每 interval 放入一个令牌，每个请求消耗一个令牌
+type TokenBucketLimiter struct {
+	capacity int
+	interval time.Duration
+	store    *store
+}
+
+type bucket struct {
+	tokens float64
+	// lastRefill 上一次计算令牌数量的时间
+	lastRefill time.Time
+}
+
+// NewTokenBucketLimiter 例如 NewTokenBucketLimiter(10, 100*time.Millisecond)
+// 表示平均每秒 10 个请求，最多允许突发 10 个请求
+func NewTokenBucketLimiter(capacity int, interval time.Duration, opts ...Option) *TokenBucketLimiter {
+	if capacity <= 0 || interval <= 0 {
+		panic("web: 令牌桶的容量和放入令牌的间隔必须大于 0")
+	}
+	return &TokenBucketLimiter{
+		capacity: capacity,
+		interval: interval,
+		// 空闲这么久之后桶就满了
+		store: newStore(time.Duration(capacity)*interval, opts),
+	}
+}
+
+func (l *TokenBucketLimiter) Allow(key string) Result {
+	return l.store.do(key, func(now time.Time) any {
+		return &bucket{tokens: float64(l.capacity), lastRefill: now}
+	}, func(state any, now time.Time) Result {
+		b := state.(*bucket)
+		b.tokens += float64(now.Sub(b.lastRefill)) / float64(l.interval)
+		if b.tokens > float64(l.capacity) {
+			b.tokens = float64(l.capacity)
+		}
+		b.lastRefill = now
+
+		res := Result{Limit: l.capacity}
+		if b.tokens >= 1 {
+			b.tokens--
+			res.Allowed = true
+		} else {
+			res.RetryAfter = l.duration(1 - b.tokens)
+		}
+		res.Remaining = int(b.tokens)
+		res.Reset = l.duration(float64(l.capacity) - b.tokens)
+		return res
+	})
+}
+
+// duration 放入 tokens 个令牌需要的时间
+func (l *TokenBucketLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens * float64(l.interval))
+}
@@ -1,7 +1,10 @@
 package web
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 )
 
@@ -9,11 +12,23 @@ type router struct {
 	// trees 是按照 HTTP 方法来组织的
 	// 如 GET => *node
 	trees map[string]*node
+
+	// names 命名路由，名字 => 路由
+	// Any 会一次注册多个 HTTP 方法，所以一个名字可能对应多个路由，它们的路径都是一样的
+	names map[string][]namedRoute
+	// lastRoutes 最近一次注册的路由，用于给路由命名
+	lastRoutes []namedRoute
+}
+
+type namedRoute struct {
+	method string
+	n      *node
 }
 
 func newRouter() router {
 	return router{
 		trees: map[string]*node{},
+		names: map[string][]namedRoute{},
 	}
 }
 
@@ -23,85 +38,316 @@ func newRouter() router {
 // - path 必须以 / 开始并且结尾不能有 /，中间也不允许有连续的 /
 // - 不能在同一个位置注册不同的参数路由，例如 /user/:id 和 /user/:name 冲突
 // - 不能在同一个位置同时注册通配符路由和参数路由，例如 /user/:id 和 /user/* 冲突
+// - 正则路由 /user/:id(^[0-9]+$) 和类型约束路由 /user/:id<int> 也不能和其它参数路由、通配符路由注册在同一个位置
+// - 支持的类型约束有 int, uint, alpha, alnum, uuid
+// - * 只匹配一段，而 /static/*filepath 这种通配符参数能够匹配剩余的任意多段，它只能是最后一段，
+// 剩余的路径会保存在路径参数 filepath 里面。它和 * 也不能注册在同一个位置
 // - 同名路径参数，在路由匹配的时候，值会被覆盖。例如 /user/:id/abc/:id，那么 /user/123/abc/456 最终 id = 456
 func (r *router) addRoute(method string, path string, handler HandleFunc, ms ...Middleware) {
-	if path == "" {
-		panic("web: 路由是空字符串")
-	}
-	if path[0] != '/' {
-		panic("web: 路由必须以 / 开头")
+	if err := r.addRouteE(method, path, handler, ms...); err != nil {
+		panic(err.Error())
 	}
+}
 
-	if path != "/" && path[len(path)-1] == '/' {
-		panic("web: 路由不能以 / 结尾")
+// addRouteE 和 addRoute 一样，但是返回 error 而不是 panic
+// 返回 error 的时候，路由树不会被修改
+func (r *router) addRouteE(method string, path string, handler HandleFunc, ms ...Middleware) error {
+	return r.addVariantE(method, path, handler, nil, nil, ms...)
+}
+
+// addVariantE 注册带有请求头约束的路由
+// 同一个路由可以注册多次，只要每一次的请求头约束不一样。没有约束的就是 addRouteE
+// groupMdls 是分组的 middleware，只作用在 handler 上，ms 则作用在所有能够匹配该路由的路由上
+func (r *router) addVariantE(method string, path string, handler HandleFunc,
+	headers []HeaderMatcher, groupMdls []Middleware, ms ...Middleware) error {
+	// 先检查 path 本身是否合法，这样后面创建节点的过程中只可能遇到冲突。
+	// 而冲突只会出现在已有的节点下面，所以也不会留下创建了一半的节点
+	if err := checkPath(path); err != nil {
+		return err
 	}
 
 	root, ok := r.trees[method]
 	// 这是一个全新的 HTTP 方法，创建根节点
+	// 等到注册成功之后才放进 trees 里面，避免失败的时候留下一棵空树，
+	// 影响 Routes 和 405 的 Allow 响应头
 	if !ok {
-		// 创建根节点
 		root = &node{path: "/"}
-		r.trees[method] = root
 	}
-	if path == "/" {
-		if root.handler != nil {
-			panic("web: 路由冲突[/]")
+	tree := root
+	if path != "/" {
+		segs := strings.Split(path[1:], "/")
+		// 开始一段段处理
+		for _, s := range segs {
+			var err error
+			root, err = root.childOrCreate(s)
+			if err != nil {
+				return err
+			}
 		}
-		root.handler = handler
-		root.mdls = ms
-		return
 	}
+	if handler != nil {
+		if len(headers) == 0 {
+			if root.handler != nil {
+				return fmt.Errorf("web: 路由冲突[%s]", path)
+			}
+			root.handler = handler
+			root.groupMdls = groupMdls
+		} else {
+			headers = canonicalHeaders(headers)
+			for _, v := range root.variants {
+				if sameHeaders(v.headers, headers) {
+					return fmt.Errorf("web: 路由冲突[%s]，请求头约束重复 %v", path, headerStrings(headers))
+				}
+			}
+			root.variants = append(root.variants, &routeVariant{headers: headers, handler: handler, groupMdls: groupMdls})
+		}
+		r.lastRoutes = []namedRoute{{method: method, n: root}}
+	}
+	if !ok {
+		r.trees[method] = tree
+	}
+	root.route = path
+	// UseV1 只注册 middleware，不注册 handler，
+	// 所以同一个路由上的 middleware 是累加的
+	root.mdls = append(root.mdls, ms...)
+	if len(ms) > 0 {
+		// 新的 middleware 可能影响任何一个已有的路由，所以整棵树都要重新计算
+		r.refreshMdls(tree)
+		return nil
+	}
+	root.refreshMdls(r.findMdls(tree, root.route))
+	return nil
+}
+
+// RouteDef 路由定义，用于批量注册路由，例如根据配置文件注册路由
+type RouteDef struct {
+	Method      string
+	Path        string
+	Handler     HandleFunc
+	Middlewares []Middleware
+	// Headers 请求头约束，可以为空
+	Headers []HeaderMatcher
+}
 
+// RouteError 某一个路由注册失败的原因
+type RouteError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Err.Error())
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// RouteErrors 批量注册路由的时候所有的错误
+type RouteErrors []*RouteError
+
+func (e RouteErrors) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("web: %d 个路由注册失败", len(e)))
+	for _, err := range e {
+		sb.WriteString("\n\t")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// addRoutes 批量注册路由
+// 要么全部注册成功，要么一个都不注册，并且返回所有的错误，而不是遇到第一个错误就返回
+func (r *router) addRoutes(defs []RouteDef) error {
+	// 先在路由树的副本上注册一遍，这样能够发现这一批路由之间的冲突，
+	// 也能够发现和已有路由之间的冲突
+	dryRun := router{trees: make(map[string]*node, len(r.trees))}
+	for method, root := range r.trees {
+		dryRun.trees[method] = root.clone()
+	}
+	var errs RouteErrors
+	for _, def := range defs {
+		if err := dryRun.addVariantE(def.Method, def.Path, def.Handler, def.Headers, nil); err != nil {
+			errs = append(errs, &RouteError{Method: def.Method, Path: def.Path, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	for _, def := range defs {
+		if err := r.addVariantE(def.Method, def.Path, def.Handler, def.Headers, nil, def.Middlewares...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPath 检查 path 是否合法，不涉及路由冲突
+func checkPath(path string) error {
+	if path == "" {
+		return errors.New("web: 路由是空字符串")
+	}
+	if path[0] != '/' {
+		return errors.New("web: 路由必须以 / 开头")
+	}
+	if path == "/" {
+		return nil
+	}
+	if path[len(path)-1] == '/' {
+		return errors.New("web: 路由不能以 / 结尾")
+	}
 	segs := strings.Split(path[1:], "/")
-	// 开始一段段处理
-	for _, s := range segs {
+	for i, s := range segs {
 		if s == "" {
-			panic(fmt.Sprintf("web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [%s]", path))
+			return fmt.Errorf("web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [%s]", path)
+		}
+		if s[0] == '*' && len(s) > 1 && i != len(segs)-1 {
+			return fmt.Errorf("web: 非法路由，通配符参数只能是最后一段 [%s]", path)
+		}
+		if s[0] != ':' {
+			continue
+		}
+		name, expr, isReg, err := parseParam(s)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("web: 非法路由，缺少参数名 [%s]", s)
+		}
+		if isReg {
+			if _, err = compileParamExpr(s, expr); err != nil {
+				return err
+			}
 		}
-		root = root.childOrCreate(s)
 	}
-	if root.handler != nil {
-		panic(fmt.Sprintf("web: 路由冲突[%s]", path))
+	return nil
+}
+
+// nameRoute 给最近一次注册的路由命名
+func (r *router) nameRoute(name string) {
+	if len(r.lastRoutes) == 0 {
+		panic("web: 还没有注册路由，无法命名")
 	}
-	root.handler = handler
-	root.route = path
-	root.mdls = ms
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("web: 路由名字冲突 [%s]", name))
+	}
+	r.names[name] = r.lastRoutes
+}
+
+// urlFor 根据命名路由反向生成 URL
+// params 是路径参数，所有的参数都必须提供，并且要满足正则路由和类型约束
+// 匿名的通配符 * 无法反向生成，*filepath 这种通配符参数的值可以包含 /
+func (r *router) urlFor(name string, params map[string]string, query url.Values) (string, error) {
+	nrs, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("web: 找不到命名路由 %s", name)
+	}
+	// 同一个名字下面的路由路径都是一样的，用哪一个都可以
+	nr := nrs[0]
+	route := nr.n.route
+	var sb strings.Builder
+	if route == "/" {
+		sb.WriteByte('/')
+	} else {
+		// 沿着路由树往下走，这样才能拿到正则路由的正则表达式
+		cur := r.trees[nr.method]
+		for _, seg := range strings.Split(route[1:], "/") {
+			sb.WriteByte('/')
+			switch seg[0] {
+			case '*':
+				cur = cur.starChild
+				if !cur.isCatchAll() {
+					return "", fmt.Errorf("web: 路由 %s 包含通配符 *，无法生成 URL", route)
+				}
+				val, ok := params[cur.paramName]
+				if !ok || val == "" {
+					return "", fmt.Errorf("web: 缺少路径参数 %s", cur.paramName)
+				}
+				vals := strings.Split(strings.Trim(val, "/"), "/")
+				for i, v := range vals {
+					vals[i] = url.PathEscape(v)
+				}
+				sb.WriteString(strings.Join(vals, "/"))
+			case ':':
+				if cur.regChild != nil && cur.regChild.path == seg {
+					cur = cur.regChild
+				} else {
+					cur = cur.paramChild
+				}
+				val, ok := params[cur.paramName]
+				if !ok || val == "" {
+					return "", fmt.Errorf("web: 缺少路径参数 %s", cur.paramName)
+				}
+				if cur.regExpr != nil && !cur.regExpr.MatchString(val) {
+					return "", fmt.Errorf("web: 路径参数 %s 的值 %s 不满足 %s", cur.paramName, val, seg)
+				}
+				sb.WriteString(url.PathEscape(val))
+			default:
+				cur = cur.children[seg]
+				sb.WriteString(seg)
+			}
+		}
+	}
+	if len(query) > 0 {
+		sb.WriteByte('?')
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
 }
 
 // findRoute 查找对应的节点
 // 注意，返回的 node 内部 HandleFunc 不为 nil 才算是注册了路由
-func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
+// 查找的时候会回溯，并且优先返回注册了 HandleFunc 的节点。
+// 例如注册了 /a/b/c 和 /a/:id，那么 /a/b 会命中 /a/:id，而不是没有 HandleFunc 的 /a/b
+// 如果所有能够匹配的节点都没有 HandleFunc，那么返回第一个匹配的节点
+func (r *router) findRoute(method string, path string) (matchInfo, bool) {
 	root, ok := r.trees[method]
 	if !ok {
-		return nil, false
+		return matchInfo{}, false
 	}
 
-	if path == "/" {
-		return &matchInfo{n: root, mdls: root.mdls}, true
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return matchInfo{n: root, mdls: root.matchedMdls}, true
 	}
 
-	segs := strings.Split(strings.Trim(path, "/"), "/")
-	mi := &matchInfo{}
-	cur := root
-	for _, s := range segs {
-		var matchParam bool
-		cur, matchParam, ok = cur.childOf(s)
+	var mi matchInfo
+	n, ok := root.match(path, true, &mi)
+	if !ok {
+		n, ok = root.match(path, false, &mi)
 		if !ok {
-			return nil, false
-		}
-		if matchParam {
-			mi.addValue(root.path[1:], s)
+			return matchInfo{}, false
 		}
 	}
-	mi.n = cur
-	mi.mdls = r.findMdls(root, segs)
+	mi.n = n
+	mi.mdls = n.matchedMdls
 	return mi, true
 }
 
-func (r *router) findMdls(root *node, segs []string) []Middleware {
-	if len(segs) == 0 {
+// refreshMdls 重新计算整棵路由树上所有路由的 matchedMdls
+func (r *router) refreshMdls(root *node) {
+	root.walk(func(n *node, depth int) {
+		if n.route != "" {
+			n.refreshMdls(r.findMdls(root, n.route))
+		}
+	})
+}
+
+// findMdls 找出能够作用在 route 上的所有 middleware
+// 也就是说，所有能够匹配 route 的路由上注册的 middleware 都会生效。
+// 计算的依据是注册的路由，而不是请求的路径，所以结果可以缓存在节点上。
+// 返回的 middleware 按照从根节点往下的顺序排列，同一层则是：
+// 1. 通配符匹配
+// 2. 路径参数匹配
+// 3. 正则匹配
+// 4. 静态完全匹配
+// 也就是越不精确的越先执行
+func (r *router) findMdls(root *node, route string) []Middleware {
+	if route == "/" {
 		return root.mdls
 	}
+	segs := strings.Split(route[1:], "/")
 	res := make([]Middleware, 0)
 	stack := []*node{root}
 	for _, s := range segs {
@@ -115,7 +361,6 @@ func (r *router) findMdls(root *node, segs []string) []Middleware {
 			children = append(children, n...)
 		}
 		stack = children
-		fmt.Println(s)
 	}
 
 	// 获取剩余栈中middleware
@@ -131,9 +376,12 @@ func (r *router) findMdls(root *node, segs []string) []Middleware {
 // node 代表路由树的节点
 // 路由树的匹配顺序是：
 // 1. 静态完全匹配
-// 2. 路径参数匹配：形式 :param_name
-// 3. 通配符匹配：*
-// 这是不回溯匹配
+// 2. 正则匹配，形式 :param_name(reg_expr) 或者 :param_name<type>
+// 3. 路径参数匹配：形式 :param_name
+// 4. 通配符匹配：* 匹配一段，*param_name 匹配剩余的任意多段
+// 这是回溯匹配，某一段按照优先级命中之后，如果后面的部分匹配不上，
+// 会退回来尝试下一个优先级的子节点。
+// 例如注册了 /a/b/c 和 /a/:id/d，那么 /a/b/d 会命中 /a/:id/d
 type node struct {
 	path string
 	// children 子节点
@@ -141,6 +389,8 @@ type node struct {
 	children map[string]*node
 	// handler 命中路由之后执行的逻辑
 	handler HandleFunc
+	// groupMdls 注册 handler 的分组上的 middleware，只作用在 handler 上
+	groupMdls []Middleware
 	// 注册在该节点上的 middleware
 	mdls []Middleware
 
@@ -148,11 +398,97 @@ type node struct {
 	route string
 
 	// 通配符 * 表达的节点，任意匹配
+	// *filepath 这种通配符参数也保存在这里
 	starChild *node
 
 	paramChild *node
+	// 正则路由、参数路由和通配符参数都会使用这个字段
+	paramName string
+
+	// 正则路由，类型约束路由也是正则路由
+	regChild *node
+	regExpr  *regexp.Regexp
 
+	// matchedMdls 命中该节点的时候需要执行的所有 middleware
+	// 包括注册在其它能够匹配该节点的路由上的 middleware
 	matchedMdls []Middleware
+	// matchedHandler 是 matchedMdls 和 handler 组装之后的结果
+	// 提前组装好，避免每个请求都组装一遍
+	matchedHandler HandleFunc
+
+	// variants 同一个路由上带有请求头约束的 HandleFunc
+	// handler 则是没有约束的那个
+	variants []*routeVariant
+}
+
+// clone 复制整棵子树，只复制注册路由需要用到的字段
+func (n *node) clone() *node {
+	res := &node{
+		path:      n.path,
+		handler:   n.handler,
+		groupMdls: n.groupMdls,
+		route:     n.route,
+		paramName: n.paramName,
+		regExpr:   n.regExpr,
+	}
+	// 复制 routeVariant 本身，而不只是复制切片，
+	// 否则在副本上调用 refreshMdls 会改掉原本的节点正在使用的 matchedHandler
+	if len(n.variants) > 0 {
+		res.variants = make([]*routeVariant, 0, len(n.variants))
+		for _, v := range n.variants {
+			res.variants = append(res.variants, &routeVariant{headers: v.headers, handler: v.handler, groupMdls: v.groupMdls})
+		}
+	}
+	if n.children != nil {
+		res.children = make(map[string]*node, len(n.children))
+		for path, child := range n.children {
+			res.children[path] = child.clone()
+		}
+	}
+	if n.starChild != nil {
+		res.starChild = n.starChild.clone()
+	}
+	if n.paramChild != nil {
+		res.paramChild = n.paramChild.clone()
+	}
+	if n.regChild != nil {
+		res.regChild = n.regChild.clone()
+	}
+	return res
+}
+
+func (n *node) refreshMdls(mdls []Middleware) {
+	n.matchedMdls = mdls
+	n.matchedHandler = nil
+	if n.handler != nil {
+		n.matchedHandler = buildChain(joinMdls(n.groupMdls, mdls), n.handler)
+	}
+	for _, v := range n.variants {
+		v.matchedHandler = buildChain(joinMdls(v.groupMdls, mdls), v.handler)
+	}
+}
+
+// joinMdls 分组的 middleware 先执行，然后才是路由上的 middleware
+func joinMdls(groupMdls []Middleware, mdls []Middleware) []Middleware {
+	if len(groupMdls) == 0 {
+		return mdls
+	}
+	res := make([]Middleware, 0, len(groupMdls)+len(mdls))
+	res = append(res, groupMdls...)
+	return append(res, mdls...)
+}
+
+// hasHandler 是否注册了 HandleFunc，包括带有请求头约束的
+func (n *node) hasHandler() bool {
+	return n.handler != nil || len(n.variants) > 0
+}
+
+func buildChain(mdls []Middleware, handler HandleFunc) HandleFunc {
+	root := handler
+	for i := len(mdls) - 1; i >= 0; i-- {
+		root = mdls[i](root)
+	}
+	return root
 }
 
 func (n *node) childrenOf(path string) []*node {
@@ -167,62 +503,118 @@ func (n *node) childrenOf(path string) []*node {
 	if n.paramChild != nil {
 		res = append(res, n.paramChild)
 	}
+	// 正则路由只能覆盖它自己，以及满足正则表达式的静态路由
+	if n.regChild != nil && (n.regChild.path == path ||
+		path[0] != ':' && path != "*" && n.regChild.regExpr.MatchString(path)) {
+		res = append(res, n.regChild)
+	}
 	if static != nil {
 		res = append(res, static)
 	}
 	return res
 }
 
-// child 返回子节点
-// 第一个返回值 *node 是命中的节点
-// 第二个返回值 bool 代表是否是命中参数路由
-// 第三个返回值 bool 代表是否命中
-func (n *node) childOf(path string) (*node, bool, bool) {
-	if n.children == nil {
-		if n.paramChild != nil {
-			return n.paramChild, true, true
+// match 在 n 的子树里面匹配 path，path 不以 / 开头
+// strict 为 true 的时候，只有注册了 HandleFunc 的节点才算是命中
+// 命中之后才会把路径参数放进 mi，所以回溯不会留下脏数据
+// 纯静态路由的匹配不会分配内存
+func (n *node) match(path string, strict bool, mi *matchInfo) (*node, bool) {
+	seg, rest := path, ""
+	last := true
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		seg, rest, last = path[:i], path[i+1:], false
+	}
+
+	if child, ok := n.children[seg]; ok {
+		if res, ok := child.matchRest(rest, last, strict, mi); ok {
+			return res, true
 		}
-		return n.starChild, false, n.starChild != nil
 	}
-	res, ok := n.children[path]
-	if !ok {
-		if n.paramChild != nil {
-			return n.paramChild, true, true
+	if n.regChild != nil && n.regChild.regExpr.MatchString(seg) {
+		if res, ok := n.regChild.matchRest(rest, last, strict, mi); ok {
+			mi.addValue(n.regChild.paramName, seg)
+			return res, true
 		}
-		return n.starChild, false, n.starChild != nil
 	}
-	return res, false, ok
+	if n.paramChild != nil {
+		if res, ok := n.paramChild.matchRest(rest, last, strict, mi); ok {
+			mi.addValue(n.paramChild.paramName, seg)
+			return res, true
+		}
+	}
+	if n.starChild != nil {
+		// 通配符参数直接匹配剩余的所有部分
+		if n.starChild.isCatchAll() {
+			if !strict || n.starChild.hasHandler() {
+				mi.addValue(n.starChild.paramName, path)
+				return n.starChild, true
+			}
+		} else if res, ok := n.starChild.matchRest(rest, last, strict, mi); ok {
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+// isCatchAll 是否是 *filepath 这种匹配任意多段的通配符参数
+func (n *node) isCatchAll() bool {
+	return n.path[0] == '*' && n.paramName != ""
+}
+
+// matchRest 在 n 已经匹配了当前这一段的情况下，继续匹配剩余部分
+func (n *node) matchRest(rest string, last bool, strict bool, mi *matchInfo) (*node, bool) {
+	if last {
+		return n, !strict || n.hasHandler()
+	}
+	return n.match(rest, strict, mi)
 }
 
 // childOrCreate 查找子节点，
 // 首先会判断 path 是不是通配符路径
-// 其次判断 path 是不是参数路径，即以 : 开头的路径
+// 其次判断 path 是不是参数路径，即以 : 开头的路径，
+// 参数路径又分为正则路径 :id(reg_expr)、类型约束路径 :id<int> 和普通的参数路径
 // 最后会从 children 里面查找，
 // 如果没有找到，那么会创建一个新的节点，并且保存在 node 里面
-func (n *node) childOrCreate(path string) *node {
-	if path == "*" {
+func (n *node) childOrCreate(path string) (*node, error) {
+	// * 或者 *filepath 这种通配符参数
+	if path[0] == '*' {
 		if n.paramChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [%s]", path))
+			return nil, fmt.Errorf("web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [%s]", path)
+		}
+		if n.regChild != nil {
+			return nil, fmt.Errorf("web: 非法路由，已有正则路由。不允许同时注册通配符路由和正则路由 [%s]", path)
 		}
 		if n.starChild == nil {
-			n.starChild = &node{path: path}
+			n.starChild = &node{path: path, paramName: path[1:]}
+		} else if n.starChild.path != path {
+			return nil, fmt.Errorf("web: 路由冲突，通配符路由冲突，已有 %s，新注册 %s", n.starChild.path, path)
 		}
-		return n.starChild
+		return n.starChild, nil
 	}
 
 	// 以 : 开头，我们认为是参数路由
 	if path[0] == ':' {
+		name, expr, isReg, err := parseParam(path)
+		if err != nil {
+			return nil, err
+		}
+		if isReg {
+			return n.regChildOrCreate(path, name, expr)
+		}
 		if n.starChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [%s]", path))
+			return nil, fmt.Errorf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [%s]", path)
+		}
+		if n.regChild != nil {
+			return nil, fmt.Errorf("web: 非法路由，已有正则路由。不允许同时注册正则路由和参数路由 [%s]", path)
 		}
 		if n.paramChild != nil {
 			if n.paramChild.path != path {
-				panic(fmt.Sprintf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, path))
+				return nil, fmt.Errorf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, path)
 			}
 		} else {
-			n.paramChild = &node{path: path}
+			n.paramChild = &node{path: path, paramName: name}
 		}
-		return n.paramChild
+		return n.paramChild, nil
 	}
 
 	if n.children == nil {
@@ -233,7 +625,68 @@ func (n *node) childOrCreate(path string) *node {
 		child = &node{path: path}
 		n.children[path] = child
 	}
-	return child
+	return child, nil
+}
+
+func (n *node) regChildOrCreate(path string, name string, expr string) (*node, error) {
+	if n.starChild != nil {
+		return nil, fmt.Errorf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和正则路由 [%s]", path)
+	}
+	if n.paramChild != nil {
+		return nil, fmt.Errorf("web: 非法路由，已有路径参数路由。不允许同时注册正则路由和参数路由 [%s]", path)
+	}
+	if n.regChild != nil {
+		if n.regChild.path != path {
+			return nil, fmt.Errorf("web: 路由冲突，正则路由冲突，已有 %s，新注册 %s", n.regChild.path, path)
+		}
+		return n.regChild, nil
+	}
+	regExpr, err := compileParamExpr(path, expr)
+	if err != nil {
+		return nil, err
+	}
+	n.regChild = &node{path: path, paramName: name, regExpr: regExpr}
+	return n.regChild, nil
+}
+
+// compileParamExpr 编译正则路由的正则表达式
+// 正则表达式必须匹配完整的一段，所以要加上 ^ 和 $
+func compileParamExpr(path string, expr string) (*regexp.Regexp, error) {
+	regExpr, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("web: 非法路由，正则表达式错误 [%s]: %w", path, err)
+	}
+	return regExpr, nil
+}
+
+// paramTypes 是内置的类型约束，例如 :id<int>
+// 本质上它们也是正则路由
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// parseParam 解析参数路径
+// 第一个返回值是参数名
+// 第二个返回值是正则表达式，类型约束也会被转化为正则表达式
+// 第三个返回值代表是不是正则路由
+func parseParam(path string) (string, string, bool, error) {
+	path = path[1:]
+	if i := strings.IndexByte(path, '('); i >= 0 && path[len(path)-1] == ')' {
+		return path[:i], path[i+1 : len(path)-1], true, nil
+	}
+	if i := strings.IndexByte(path, '<'); i >= 0 && path[len(path)-1] == '>' {
+		typ := path[i+1 : len(path)-1]
+		expr, ok := paramTypes[typ]
+		if !ok {
+			return "", "", false, fmt.Errorf("web: 非法路由，不支持的参数类型 %s [:%s]", typ, path)
+		}
+		return path[:i], expr, true, nil
+	}
+	return path, "", false, nil
 }
 
 type matchInfo struct {
@@ -242,10 +695,17 @@ type matchInfo struct {
 	mdls       []Middleware
 }
 
+// addValue 添加路径参数
+// 匹配是从后往前添加路径参数的，所以已经存在的 key 不会被覆盖，
+// 这样同名路径参数最终保留的是后面那一段的值
 func (m *matchInfo) addValue(key string, value string) {
 	if m.pathParams == nil {
 		// 大多数情况，参数路径只会有一段
 		m.pathParams = map[string]string{key: value}
+		return
+	}
+	if _, ok := m.pathParams[key]; ok {
+		return
 	}
 	m.pathParams[key] = value
 }
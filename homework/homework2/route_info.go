@@ -0,0 +1,151 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// RouteInfo 已注册路由的信息
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	// Name 命名路由的名字，没有命名的话就是空字符串
+	Name string `json:"name,omitempty"`
+	// Handler HandleFunc 的函数名
+	Handler string `json:"handler"`
+	// Middlewares 作用在这个路由上的 middleware 数量，包括分组的，不包括 Use 注册的
+	Middlewares int `json:"middlewares"`
+	// Headers 请求头约束，参考 HTTPServer.HandleWith
+	Headers []string `json:"headers,omitempty"`
+}
+
+// Routes 返回所有注册了 HandleFunc 的路由
+// 结果按照 HTTP 方法和路由排序，所以可以直接用来比较两个版本之间的路由差异
+func (s *HTTPServer) Routes() []RouteInfo {
+	names := make(map[*node]string, len(s.names))
+	for name, nrs := range s.names {
+		for _, nr := range nrs {
+			names[nr.n] = name
+		}
+	}
+	res := make([]RouteInfo, 0, 16)
+	for method, root := range s.trees {
+		root.walk(func(n *node, depth int) {
+			if n.handler != nil {
+				res = append(res, RouteInfo{
+					Method:      method,
+					Pattern:     n.route,
+					Name:        names[n],
+					Handler:     funcName(n.handler),
+					Middlewares: len(n.groupMdls) + len(n.matchedMdls),
+				})
+			}
+			for _, v := range n.variants {
+				res = append(res, RouteInfo{
+					Method:      method,
+					Pattern:     n.route,
+					Name:        names[n],
+					Handler:     funcName(v.handler),
+					Middlewares: len(v.groupMdls) + len(n.matchedMdls),
+					Headers:     headerStrings(v.headers),
+				})
+			}
+		})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Method != res[j].Method {
+			return res[i].Method < res[j].Method
+		}
+		if res[i].Pattern != res[j].Pattern {
+			return res[i].Pattern < res[j].Pattern
+		}
+		return len(res[i].Headers) < len(res[j].Headers)
+	})
+	return res
+}
+
+// RoutesHandler 返回一个用于调试的 HandleFunc，输出所有的路由
+// 默认输出路由树的文本形式，带上查询参数 format=json 的时候输出 Routes 的 JSON
+// 一般只应该在内部的调试端口上注册，例如 s.Get("/debug/routes", s.RoutesHandler())
+func (s *HTTPServer) RoutesHandler() HandleFunc {
+	return func(ctx *Context) {
+		if ctx.Req.URL.Query().Get("format") == "json" {
+			_ = ctx.RespJSONOK(s.Routes())
+			return
+		}
+		ctx.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = []byte(s.routeTree())
+	}
+}
+
+// routeTree 以文本形式输出路由树，例如：
+//
+//	GET
+//	/ [main.home]
+//	    user
+//	        :id [main.user mdls=1]
+func (s *HTTPServer) routeTree() string {
+	methods := make([]string, 0, len(s.trees))
+	for method := range s.trees {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var sb strings.Builder
+	for _, method := range methods {
+		sb.WriteString(method)
+		sb.WriteByte('\n')
+		s.trees[method].walk(func(n *node, depth int) {
+			sb.WriteString(strings.Repeat("    ", depth))
+			sb.WriteString(n.path)
+			if n.handler != nil {
+				sb.WriteString(" [")
+				sb.WriteString(funcName(n.handler))
+				if cnt := len(n.groupMdls) + len(n.matchedMdls); cnt > 0 {
+					sb.WriteString(fmt.Sprintf(" mdls=%d", cnt))
+				}
+				sb.WriteByte(']')
+			}
+			for _, v := range n.variants {
+				sb.WriteString(fmt.Sprintf(" [%s %v]", funcName(v.handler), headerStrings(v.headers)))
+			}
+			sb.WriteByte('\n')
+		})
+	}
+	return sb.String()
+}
+
+// walk 深度优先遍历路由树
+// 同一层按照静态、正则、参数、通配符的顺序遍历，静态节点之间按照 path 排序
+func (n *node) walk(fn func(n *node, depth int)) {
+	n.walkDepth(fn, 0)
+}
+
+func (n *node) walkDepth(fn func(n *node, depth int), depth int) {
+	fn(n, depth)
+	paths := make([]string, 0, len(n.children))
+	for path := range n.children {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		n.children[path].walkDepth(fn, depth+1)
+	}
+	if n.regChild != nil {
+		n.regChild.walkDepth(fn, depth+1)
+	}
+	if n.paramChild != nil {
+		n.paramChild.walkDepth(fn, depth+1)
+	}
+	if n.starChild != nil {
+		n.starChild.walkDepth(fn, depth+1)
+	}
+}
+
+func funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func mockUserHandler(ctx *Context) {}
+
+func mockOrderHandler(ctx *Context) {}
+
+func TestHTTPServer_Routes(t *testing.T) {
+	var mdl Middleware = func(next HandleFunc) HandleFunc {
+		return next
+	}
+	s := NewHTTPServer()
+	s.Post("/order", mockOrderHandler)
+	s.Get("/user/:id", mockUserHandler).Name("user")
+	s.Get("/user/home", mockUserHandler)
+	s.Get("/", mockUserHandler)
+	s.UseV1(http.MethodGet, "/user/:id", mdl, mdl)
+
+	wantRoutes := []RouteInfo{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/",
+			Handler: "homework/homework2.mockUserHandler",
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/user/:id",
+			Name:        "user",
+			Handler:     "homework/homework2.mockUserHandler",
+			Middlewares: 2,
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/user/home",
+			Handler:     "homework/homework2.mockUserHandler",
+			Middlewares: 2,
+		},
+		{
+			Method:  http.MethodPost,
+			Pattern: "/order",
+			Handler: "homework/homework2.mockOrderHandler",
+		},
+	}
+	assert.Equal(t, wantRoutes, s.Routes())
+
+	s.Get("/debug/routes", s.RoutesHandler())
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/routes?format=json", nil))
+	var routes []RouteInfo
+	err := json.Unmarshal(recorder.Body.Bytes(), &routes)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(routes))
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	wantTree := `GET
+/ [homework/homework2.mockUserHandler]
+    debug
+        routes [homework/homework2.(*HTTPServer).RoutesHandler.func1]
+    user
+        home [homework/homework2.mockUserHandler mdls=2]
+        :id [homework/homework2.mockUserHandler mdls=2]
+POST
+/
+    order [homework/homework2.mockOrderHandler]
+`
+	assert.Equal(t, wantTree, recorder.Body.String())
+}
@@ -577,4 +577,342 @@ func Test_findRoute_Middleware(t *testing.T) {
 		})
 	}
 
-}
\ No newline at end of file
+}
+
+func Test_router_findRoute_Backtracking(t *testing.T) {
+	testRoutes := []string{
+		"/a/b/c",
+		"/a/:id/d",
+		"/a/:id/*/f",
+		"/*/b/e",
+		"/x/y/z",
+		"/x/:name",
+		"/user/:id/abc/:id",
+	}
+	r := newRouter()
+	for _, route := range testRoutes {
+		route := route
+		r.addRoute(http.MethodGet, route, func(ctx *Context) {
+			ctx.MatchedRoute = route
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		found      bool
+		wantRoute  string
+		wantParams map[string]string
+	}{
+		{
+			name:      "static",
+			path:      "/a/b/c",
+			found:     true,
+			wantRoute: "/a/b/c",
+		},
+		{
+			// 静态节点 b 下面没有 d，回溯到 :id
+			name:       "backtrack to param",
+			path:       "/a/b/d",
+			found:      true,
+			wantRoute:  "/a/:id/d",
+			wantParams: map[string]string{"id": "b"},
+		},
+		{
+			name:       "backtrack in deep",
+			path:       "/a/b/c/f",
+			found:      true,
+			wantRoute:  "/a/:id/*/f",
+			wantParams: map[string]string{"id": "b"},
+		},
+		{
+			// a 和 :id 下面都没有 e，一路回溯到根节点的 *
+			name:      "backtrack to star",
+			path:      "/a/b/e",
+			found:     true,
+			wantRoute: "/*/b/e",
+		},
+		{
+			// /x/y 只是中间节点，没有 HandleFunc
+			name:       "skip node without handler",
+			path:       "/x/y",
+			found:      true,
+			wantRoute:  "/x/:name",
+			wantParams: map[string]string{"name": "y"},
+		},
+		{
+			name:       "same param name",
+			path:       "/user/123/abc/456",
+			found:      true,
+			wantRoute:  "/user/:id/abc/:id",
+			wantParams: map[string]string{"id": "456"},
+		},
+		{
+			name: "not found",
+			path: "/a/b/c/d",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.Equal(t, tc.found, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantParams, mi.pathParams)
+			ctx := &Context{}
+			mi.n.handler(ctx)
+			assert.Equal(t, tc.wantRoute, ctx.MatchedRoute)
+		})
+	}
+}
+
+func Test_router_findRoute_Allocs(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/user/home", func(ctx *Context) {})
+	r.addRoute(http.MethodGet, "/user/:id/detail", func(ctx *Context) {})
+	allocs := testing.AllocsPerRun(100, func() {
+		r.findRoute(http.MethodGet, "/user/home")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func Test_router_RegRoute(t *testing.T) {
+	testRoutes := []string{
+		"/user/:id(^[0-9]+$)",
+		"/user/:id(^[0-9]+$)/detail",
+		"/user/home",
+		"/order/:id<int>",
+		"/order/latest",
+		"/order/:id<int>/:sub<uuid>",
+		"/item/:name<alpha>",
+		"/item/:name<alpha>/:code<alnum>/:count<uint>",
+		"/file/:name([a-z]+\\.txt)",
+	}
+	r := newRouter()
+	for _, route := range testRoutes {
+		route := route
+		r.addRoute(http.MethodGet, route, func(ctx *Context) {
+			ctx.MatchedRoute = route
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		found      bool
+		wantRoute  string
+		wantParams map[string]string
+	}{
+		{
+			name:       "reg",
+			path:       "/user/123",
+			found:      true,
+			wantRoute:  "/user/:id(^[0-9]+$)",
+			wantParams: map[string]string{"id": "123"},
+		},
+		{
+			name:      "static first",
+			path:      "/user/home",
+			found:     true,
+			wantRoute: "/user/home",
+		},
+		{
+			name: "reg not match",
+			path: "/user/abc",
+		},
+		{
+			name:       "reg in middle",
+			path:       "/user/123/detail",
+			found:      true,
+			wantRoute:  "/user/:id(^[0-9]+$)/detail",
+			wantParams: map[string]string{"id": "123"},
+		},
+		{
+			name:       "int",
+			path:       "/order/-12",
+			found:      true,
+			wantRoute:  "/order/:id<int>",
+			wantParams: map[string]string{"id": "-12"},
+		},
+		{
+			name: "int not match",
+			path: "/order/12a",
+		},
+		{
+			name:       "uuid",
+			path:       "/order/12/123e4567-e89b-12d3-a456-426614174000",
+			found:      true,
+			wantRoute:  "/order/:id<int>/:sub<uuid>",
+			wantParams: map[string]string{"id": "12", "sub": "123e4567-e89b-12d3-a456-426614174000"},
+		},
+		{
+			name: "uuid not match",
+			path: "/order/12/abc",
+		},
+		{
+			name:      "static and int",
+			path:      "/order/latest",
+			found:     true,
+			wantRoute: "/order/latest",
+		},
+		{
+			name:       "alpha alnum uint",
+			path:       "/item/apple/a1/10",
+			found:      true,
+			wantRoute:  "/item/:name<alpha>/:code<alnum>/:count<uint>",
+			wantParams: map[string]string{"name": "apple", "code": "a1", "count": "10"},
+		},
+		{
+			name: "uint not match",
+			path: "/item/apple/a1/-10",
+		},
+		{
+			// 正则表达式会匹配完整的一段
+			name: "full match",
+			path: "/file/a.txt.bak",
+		},
+		{
+			name:       "file",
+			path:       "/file/a.txt",
+			found:      true,
+			wantRoute:  "/file/:name([a-z]+\\.txt)",
+			wantParams: map[string]string{"name": "a.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.Equal(t, tc.found, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantParams, mi.pathParams)
+			ctx := &Context{}
+			mi.n.handler(ctx)
+			assert.Equal(t, tc.wantRoute, ctx.MatchedRoute)
+		})
+	}
+
+	// 非法用例
+	mockHandler := func(ctx *Context) {}
+	r = newRouter()
+	assert.PanicsWithValue(t, "web: 非法路由，已有正则路由。不允许同时注册正则路由和参数路由 [:name]", func() {
+		r.addRoute(http.MethodGet, "/a/:id(^[0-9]+$)", mockHandler)
+		r.addRoute(http.MethodGet, "/a/:name", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，已有正则路由。不允许同时注册通配符路由和正则路由 [*]", func() {
+		r.addRoute(http.MethodGet, "/a/*", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 路由冲突，正则路由冲突，已有 :id(^[0-9]+$)，新注册 :id<int>", func() {
+		r.addRoute(http.MethodGet, "/a/:id<int>", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，已有路径参数路由。不允许同时注册正则路由和参数路由 [:id<int>]", func() {
+		r.addRoute(http.MethodGet, "/b/:id", mockHandler)
+		r.addRoute(http.MethodGet, "/b/:id<int>", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，已有通配符路由。不允许同时注册通配符路由和正则路由 [:id<int>]", func() {
+		r.addRoute(http.MethodGet, "/c/*", mockHandler)
+		r.addRoute(http.MethodGet, "/c/:id<int>", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，不支持的参数类型 float [:id<float>]", func() {
+		r.addRoute(http.MethodGet, "/d/:id<float>", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，缺少参数名 [:<int>]", func() {
+		r.addRoute(http.MethodGet, "/e/:<int>", mockHandler)
+	})
+	assert.Panics(t, func() {
+		r.addRoute(http.MethodGet, "/f/:id([0-9]+", mockHandler)
+		r.addRoute(http.MethodGet, "/g/:id(a(b)", mockHandler)
+	})
+}
+
+func Test_router_CatchAll(t *testing.T) {
+	testRoutes := []string{
+		"/static/*filepath",
+		"/static/index",
+		"/proxy/:service/*path",
+		"/*all",
+	}
+	r := newRouter()
+	for _, route := range testRoutes {
+		route := route
+		r.addRoute(http.MethodGet, route, func(ctx *Context) {
+			ctx.MatchedRoute = route
+		})
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		found      bool
+		wantRoute  string
+		wantParams map[string]string
+	}{
+		{
+			name:       "one segment",
+			path:       "/static/app.js",
+			found:      true,
+			wantRoute:  "/static/*filepath",
+			wantParams: map[string]string{"filepath": "app.js"},
+		},
+		{
+			name:       "multiple segments",
+			path:       "/static/css/theme/app.css",
+			found:      true,
+			wantRoute:  "/static/*filepath",
+			wantParams: map[string]string{"filepath": "css/theme/app.css"},
+		},
+		{
+			name:      "static first",
+			path:      "/static/index",
+			found:     true,
+			wantRoute: "/static/index",
+		},
+		{
+			name:       "with param",
+			path:       "/proxy/user/v1/user/123",
+			found:      true,
+			wantRoute:  "/proxy/:service/*path",
+			wantParams: map[string]string{"service": "user", "path": "v1/user/123"},
+		},
+		{
+			// 通配符参数至少要匹配一段，所以回溯到 /*all
+			name:       "empty remaining",
+			path:       "/proxy/user",
+			found:      true,
+			wantRoute:  "/*all",
+			wantParams: map[string]string{"all": "proxy/user"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.Equal(t, tc.found, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantParams, mi.pathParams)
+			ctx := &Context{}
+			mi.n.handler(ctx)
+			assert.Equal(t, tc.wantRoute, ctx.MatchedRoute)
+		})
+	}
+
+	// 非法用例
+	mockHandler := func(ctx *Context) {}
+	r = newRouter()
+	assert.PanicsWithValue(t, "web: 非法路由，通配符参数只能是最后一段 [/a/*file/b]", func() {
+		r.addRoute(http.MethodGet, "/a/*file/b", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 路由冲突，通配符路由冲突，已有 *file，新注册 *", func() {
+		r.addRoute(http.MethodGet, "/a/*file", mockHandler)
+		r.addRoute(http.MethodGet, "/a/*", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [:id]", func() {
+		r.addRoute(http.MethodGet, "/a/:id", mockHandler)
+	})
+}
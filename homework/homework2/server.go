@@ -3,6 +3,10 @@ package web
 import (
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type HandleFunc func(ctx *Context)
@@ -27,15 +31,45 @@ var _ Server = &HTTPServer{}
 type HTTPServer struct {
 	router
 	mdls []Middleware
+
+	// handler 是组装好的 middleware 链条
+	// 由 Freeze 组装，之后每个请求都直接使用
+	handler    HandleFunc
+	freezeOnce sync.Once
+
+	// hosts 其它域名的 HTTPServer，当前 HTTPServer 是默认的域名
+	hosts hosts
+
+	tplEngine TemplateEngine
+
+	// wsConns 升级成 WebSocket 的连接，和域名的 HTTPServer 共享
+	wsConns *wsConns
+}
+
+type HTTPServerOption func(server *HTTPServer)
+
+func NewHTTPServer(opts ...HTTPServerOption) *HTTPServer {
+	res := &HTTPServer{
+		router:  newRouter(),
+		wsConns: newWSConns(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
 }
 
-func NewHTTPServer() *HTTPServer {
-	return &HTTPServer{
-		router: newRouter(),
+// ServerWithTemplateEngine 设置模板引擎，之后可以使用 Context.Render
+func ServerWithTemplateEngine(engine TemplateEngine) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.tplEngine = engine
 	}
 }
 
 func (s *HTTPServer) Use(mdls ...Middleware) *HTTPServer {
+	if s.handler != nil {
+		panic("web: HTTPServer 已经组装好了 middleware，不能再调用 Use")
+	}
 	if s.mdls == nil {
 		s.mdls = mdls
 		return s
@@ -46,65 +80,246 @@ func (s *HTTPServer) Use(mdls ...Middleware) *HTTPServer {
 
 // UseV1 会执行路由匹配，只有匹配上了的 mdls 才会生效
 // 这个只需要稍微改造一下路由树就可以实现
+// 例如 UseV1(http.MethodGet, "/user/*", mdl)，那么 /user/home 和 /user/:id 都会执行 mdl
+// 执行顺序是越不精确的越先执行，并且都在 Use 注册的 middleware 之后执行
 func (s *HTTPServer) UseV1(method string, path string, mdls ...Middleware) {
 	s.addRoute(method, path, nil, mdls...)
 }
 
 // ServeHTTP HTTPServer 处理请求的入口
 func (s *HTTPServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// 先按照域名分发，找不到的才由当前 HTTPServer 处理
+	if hs := s.hosts.find(request.Host); hs != nil {
+		hs.ServeHTTP(writer, request)
+		return
+	}
 	ctx := &Context{
-		Req:  request,
-		Resp: writer,
-	}
-	// 最后一个应该是 HTTPServer 执行路由匹配，执行用户代码
-	root := s.serve
-	// 从后往前组装
-	for i := len(s.mdls) - 1; i >= 0; i-- {
-		root = s.mdls[i](root)
-	}
-	// 第一个应该是回写响应的
-	// 因为它在调用next之后才回写响应，
-	// 所以实际上 flashResp 是最后一个步骤
-	var m Middleware = func(next HandleFunc) HandleFunc {
-		return func(ctx *Context) {
-			next(ctx)
-			s.flashResp(ctx)
-		}
+		Req:       request,
+		Resp:      writer,
+		tplEngine: s.tplEngine,
+		wsConns:   s.wsConns,
 	}
-	root = m(root)
-	root(ctx)
+	// 没有调用 Start 而是直接当作 http.Handler 使用的时候，
+	// 在第一个请求到来的时候组装
+	s.Freeze()
+	s.handler(ctx)
+}
+
+// Freeze 组装 middleware 链条，只会组装一次
+// Start 会调用 Freeze，在这之后就不能再调用 Use 了
+// 路由上的 middleware 是在注册路由的时候组装的，不受影响
+func (s *HTTPServer) Freeze() {
+	s.freezeOnce.Do(func() {
+		// 最后一个应该是 HTTPServer 执行路由匹配，执行用户代码
+		root := s.serve
+		// 从后往前组装
+		for i := len(s.mdls) - 1; i >= 0; i-- {
+			root = s.mdls[i](root)
+		}
+		// 第一个应该是回写响应的
+		// 因为它在调用next之后才回写响应，
+		// 所以实际上 flashResp 是最后一个步骤
+		var m Middleware = func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				next(ctx)
+				s.flashResp(ctx)
+			}
+		}
+		s.handler = m(root)
+	})
 }
 
 // Start 启动服务器
 func (s *HTTPServer) Start(addr string) error {
+	s.Freeze()
 	return http.ListenAndServe(addr, s)
 }
 
-func (s *HTTPServer) Post(path string, handler HandleFunc) *HTTPServer {
-	s.addRoute(http.MethodPost, path, handler)
+// HandleWith 注册带有请求头约束的路由
+// 同一个路由可以用不同的约束注册多次，命中路由之后：
+// 1. 在所有满足的约束里面，选择约束最多的那个，约束数量一样的，选择先注册的
+// 2. 都不满足的话，使用没有约束的路由，即用 Get 等方法注册的
+// 3. 没有这样的路由，那么返回 406
+// 例如 s.HandleWith(http.MethodGet, "/user", handler, web.Version("v2"))
+func (s *HTTPServer) HandleWith(method string, path string, handler HandleFunc, headers ...HeaderMatcher) *HTTPServer {
+	if err := s.addVariantE(method, path, handler, headers, nil); err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+// AddRoutes 批量注册路由，适用于根据配置注册路由的场景
+// 和 Get 等方法不同，它不会 panic，而是返回所有路由的错误，类型是 RouteErrors
+// 只要有一个路由有问题，那么所有的路由都不会被注册
+func (s *HTTPServer) AddRoutes(defs ...RouteDef) error {
+	return s.addRoutes(defs)
+}
+
+// Name 给最近一次注册的路由命名，之后可以使用 URLFor 反向生成 URL
+// 例如 s.Get("/user/:id", handler).Name("user-detail")
+func (s *HTTPServer) Name(name string) *HTTPServer {
+	s.nameRoute(name)
+	return s
+}
+
+// URLFor 根据命名路由生成 URL
+// params 是路径参数，query 是查询参数，可以为 nil
+// 例如 /user/:id 命名为 user-detail，那么
+// URLFor("user-detail", map[string]string{"id": "123"}, nil) 返回 /user/123
+func (s *HTTPServer) URLFor(name string, params map[string]string, query url.Values) (string, error) {
+	return s.urlFor(name, params, query)
+}
+
+// Handle 注册任意 HTTP 方法的路由
+func (s *HTTPServer) Handle(method string, path string, handler HandleFunc) *HTTPServer {
+	s.addRoute(method, path, handler)
 	return s
 }
 
 func (s *HTTPServer) Get(path string, handler HandleFunc) *HTTPServer {
-	s.addRoute(http.MethodGet, path, handler)
+	return s.Handle(http.MethodGet, path, handler)
+}
+
+func (s *HTTPServer) Post(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodPost, path, handler)
+}
+
+func (s *HTTPServer) Put(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodPut, path, handler)
+}
+
+func (s *HTTPServer) Delete(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodDelete, path, handler)
+}
+
+func (s *HTTPServer) Patch(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodPatch, path, handler)
+}
+
+// Head 注册 HEAD 路由
+// 一般来说不需要注册，没有 HEAD 路由的时候会使用 GET 路由来处理 HEAD 请求
+func (s *HTTPServer) Head(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodHead, path, handler)
+}
+
+// Options 注册 OPTIONS 路由
+// 没有注册的时候，OPTIONS 请求会返回 204，并且在 Allow 里面列出该路径支持的方法
+func (s *HTTPServer) Options(path string, handler HandleFunc) *HTTPServer {
+	return s.Handle(http.MethodOptions, path, handler)
+}
+
+// Any 在所有的标准 HTTP 方法上注册同一个路由
+// 之后调用 Name 的话，这些路由都会使用同一个名字
+func (s *HTTPServer) Any(path string, handler HandleFunc) *HTTPServer {
+	routes := make([]namedRoute, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		s.Handle(method, path, handler)
+		routes = append(routes, s.lastRoutes...)
+	}
+	s.lastRoutes = routes
 	return s
 }
 
+// anyMethods 是 Any 会注册的 HTTP 方法
+// 不包括 CONNECT 和 TRACE，它们的语义和普通的路由完全不一样，需要的话单独注册
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
 func (s *HTTPServer) serve(ctx *Context) {
-	mi, ok := s.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
-	if !ok || mi.n == nil || mi.n.handler == nil {
-		ctx.RespStatusCode = 404
+	method, path := ctx.Req.Method, ctx.Req.URL.Path
+	mi, ok := s.findHandler(method, path)
+	// HEAD 请求没有对应的路由，那么就交给 GET 路由处理
+	// 响应体会被 http.Server 丢弃
+	if !ok && method == http.MethodHead {
+		mi, ok = s.findHandler(http.MethodGet, path)
+	}
+	if ok {
+		ctx.PathParams = mi.pathParams
+		ctx.MatchedRoute = mi.n.route
+		handler, ok := mi.n.selectHandler(ctx.Req)
+		// 路由存在，但是请求头不满足任何一个约束
+		if !ok {
+			ctx.RespStatusCode = http.StatusNotAcceptable
+			return
+		}
+		// 路由上的 middleware 在 HTTPServer 的 middleware 之后执行
+		handler(ctx)
+		return
+	}
+
+	// 在别的方法下面注册了这个路径，那么返回 405 或者响应 OPTIONS
+	allowed := s.allowedMethods(path)
+	if len(allowed) == 0 {
+		ctx.RespStatusCode = http.StatusNotFound
+		return
+	}
+	ctx.Resp.Header().Set("Allow", strings.Join(allowed, ", "))
+	if method == http.MethodOptions {
+		ctx.RespStatusCode = http.StatusNoContent
 		return
 	}
-	ctx.PathParams = mi.pathParams
-	ctx.MatchedRoute = mi.n.route
-	mi.n.handler(ctx)
+	ctx.RespStatusCode = http.StatusMethodNotAllowed
+}
+
+// findHandler 查找路由，并且只有注册了 handler 才算是找到
+func (s *HTTPServer) findHandler(method string, path string) (matchInfo, bool) {
+	mi, ok := s.findRoute(method, path)
+	if !ok || !mi.n.hasHandler() {
+		return matchInfo{}, false
+	}
+	return mi, true
+}
+
+// allowedMethods 返回 path 支持的所有 HTTP 方法，按照字母序排列
+// 注册了 GET 就意味着支持 HEAD，而 OPTIONS 总是支持的
+func (s *HTTPServer) allowedMethods(path string) []string {
+	res := make([]string, 0, len(s.trees)+2)
+	for method := range s.trees {
+		if _, ok := s.findHandler(method, path); ok {
+			res = append(res, method)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	if contains(res, http.MethodGet) && !contains(res, http.MethodHead) {
+		res = append(res, http.MethodHead)
+	}
+	if !contains(res, http.MethodOptions) {
+		res = append(res, http.MethodOptions)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func contains(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *HTTPServer) flashResp(ctx *Context) {
+	// 流式响应已经把数据发送出去了，WebSocket 的连接也已经不归我们管了
+	if ctx.Streaming() || ctx.hijacked {
+		return
+	}
 	if ctx.RespStatusCode > 0 {
 		ctx.Resp.WriteHeader(ctx.RespStatusCode)
 	}
+	// 像 204 这种响应是不允许有响应体的，写入空的响应体也会返回错误
+	if len(ctx.RespData) == 0 {
+		return
+	}
 	_, err := ctx.Resp.Write(ctx.RespData)
 	if err != nil {
 		log.Fatalln("回写响应失败", err)
@@ -1 +1,469 @@
 package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHTTPServer_UseV1(t *testing.T) {
+	var mdlBuilder = func(i byte) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				ctx.RespData = append(ctx.RespData, i)
+				next(ctx)
+			}
+		}
+	}
+	var handler = func(ctx *Context) {
+		ctx.RespData = append(ctx.RespData, '!')
+	}
+
+	s := NewHTTPServer()
+	s.Use(mdlBuilder('s'))
+	s.Get("/", handler)
+	s.Get("/user/home", handler)
+	s.Get("/user/:id", handler)
+	s.Get("/order/detail", handler)
+	s.Get("/login", handler)
+	// 先注册路由，再注册 middleware
+	s.UseV1(http.MethodGet, "/order/*", mdlBuilder('*'))
+	s.UseV1(http.MethodGet, "/user/home", mdlBuilder('h'))
+	s.UseV1(http.MethodGet, "/user/:id", mdlBuilder(':'))
+	s.UseV1(http.MethodGet, "/", mdlBuilder('/'))
+	// 同一个路由多次注册 middleware
+	s.UseV1(http.MethodGet, "/user/home", mdlBuilder('H'))
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantResp string
+	}{
+		{
+			name:     "root",
+			path:     "/",
+			wantResp: "s/!",
+		},
+		{
+			name:     "static",
+			path:     "/user/home",
+			wantResp: "s/:hH!",
+		},
+		{
+			name:     "param",
+			path:     "/user/123",
+			wantResp: "s/:!",
+		},
+		{
+			name:     "star",
+			path:     "/order/detail",
+			wantResp: "s/*!",
+		},
+		{
+			name:     "no route middleware",
+			path:     "/login",
+			wantResp: "s/!",
+		},
+		{
+			name:     "not found",
+			path:     "/order",
+			wantResp: "s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+		})
+	}
+}
+
+func TestHTTPServer_Freeze(t *testing.T) {
+	s := NewHTTPServer()
+	s.Get("/user", func(ctx *Context) {
+		ctx.RespData = []byte("hello, user")
+	})
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, "hello, user", recorder.Body.String())
+	assert.PanicsWithValue(t, "web: HTTPServer 已经组装好了 middleware，不能再调用 Use", func() {
+		s.Use(func(next HandleFunc) HandleFunc {
+			return next
+		})
+	})
+}
+
+func TestHTTPServer_Methods(t *testing.T) {
+	var handlerBuilder = func(resp string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.RespData = []byte(resp)
+		}
+	}
+	s := NewHTTPServer()
+	s.Get("/user", handlerBuilder("get user"))
+	s.Post("/user", handlerBuilder("post user"))
+	s.Put("/user/:id", handlerBuilder("put user"))
+	s.Patch("/user/:id", handlerBuilder("patch user"))
+	s.Delete("/user/:id", handlerBuilder("delete user"))
+	s.Head("/order", handlerBuilder(""))
+	s.Options("/order", func(ctx *Context) {
+		ctx.Resp.Header().Set("Allow", "HEAD, OPTIONS")
+	})
+	s.Handle("PURGE", "/cache", handlerBuilder("purge cache"))
+	s.Any("/any", handlerBuilder("any"))
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantResp  string
+		wantAllow string
+	}{
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantResp: "get user",
+		},
+		{
+			name:     "patch",
+			method:   http.MethodPatch,
+			path:     "/user/123",
+			wantCode: http.StatusOK,
+			wantResp: "patch user",
+		},
+		{
+			name:     "custom method",
+			method:   "PURGE",
+			path:     "/cache",
+			wantCode: http.StatusOK,
+			wantResp: "purge cache",
+		},
+		{
+			name:     "any",
+			method:   http.MethodPatch,
+			path:     "/any",
+			wantCode: http.StatusOK,
+			wantResp: "any",
+		},
+		{
+			// Any 不会注册 CONNECT 和 TRACE
+			name:      "any trace",
+			method:    http.MethodTrace,
+			path:      "/any",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT",
+		},
+		{
+			// httptest.ResponseRecorder 不会丢弃 HEAD 的响应体
+			name:     "head from get",
+			method:   http.MethodHead,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantResp: "get user",
+		},
+		{
+			name:      "method not allowed",
+			method:    http.MethodDelete,
+			path:      "/user",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			name:      "method not allowed with param",
+			method:    http.MethodGet,
+			path:      "/user/123",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "DELETE, OPTIONS, PATCH, PUT",
+		},
+		{
+			name:      "auto options",
+			method:    http.MethodOptions,
+			path:      "/user",
+			wantCode:  http.StatusNoContent,
+			wantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			name:      "registered options",
+			method:    http.MethodOptions,
+			path:      "/order",
+			wantCode:  http.StatusOK,
+			wantAllow: "HEAD, OPTIONS",
+		},
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/order/123",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "options not found",
+			method:   http.MethodOptions,
+			path:     "/order/123",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestHTTPServer_URLFor(t *testing.T) {
+	handler := func(ctx *Context) {}
+	s := NewHTTPServer()
+	s.Get("/", handler).Name("home")
+	s.Get("/user/:id", handler).Name("user")
+	s.Get("/order/:id<int>/item/:name", handler).Name("order-item")
+	s.Get("/static/*filepath", handler).Name("static")
+	s.Get("/any/*", handler).Name("any")
+	s.Group("/api/v1").Post("/login", handler).Name("login")
+	s.Any("/ping", handler).Name("ping")
+	s.Group("/api/v1").Any("/ping", handler).Name("api-ping")
+	// Any 注册的所有路由都使用同一个名字
+	named := make(map[string]int, 2)
+	for _, route := range s.Routes() {
+		named[route.Name]++
+	}
+	assert.Equal(t, len(anyMethods), named["ping"])
+	assert.Equal(t, len(anyMethods), named["api-ping"])
+
+	assert.PanicsWithValue(t, "web: 路由名字冲突 [user]", func() {
+		s.Name("user")
+	})
+	assert.PanicsWithValue(t, "web: 还没有注册路由，无法命名", func() {
+		NewHTTPServer().Name("user")
+	})
+
+	testCases := []struct {
+		name      string
+		routeName string
+		params    map[string]string
+		query     url.Values
+		wantURL   string
+		wantErr   string
+	}{
+		{
+			name:      "root",
+			routeName: "home",
+			wantURL:   "/",
+		},
+		{
+			name:      "any",
+			routeName: "api-ping",
+			wantURL:   "/api/v1/ping",
+		},
+		{
+			name:      "param",
+			routeName: "user",
+			params:    map[string]string{"id": "123"},
+			wantURL:   "/user/123",
+		},
+		{
+			name:      "escape",
+			routeName: "user",
+			params:    map[string]string{"id": "a b/c"},
+			wantURL:   "/user/a%20b%2Fc",
+		},
+		{
+			name:      "query",
+			routeName: "user",
+			params:    map[string]string{"id": "123"},
+			query:     url.Values{"page": []string{"1"}, "size": []string{"10"}},
+			wantURL:   "/user/123?page=1&size=10",
+		},
+		{
+			name:      "typed",
+			routeName: "order-item",
+			params:    map[string]string{"id": "12", "name": "apple"},
+			wantURL:   "/order/12/item/apple",
+		},
+		{
+			name:      "typed not match",
+			routeName: "order-item",
+			params:    map[string]string{"id": "abc", "name": "apple"},
+			wantErr:   "web: 路径参数 id 的值 abc 不满足 :id<int>",
+		},
+		{
+			name:      "missing param",
+			routeName: "order-item",
+			params:    map[string]string{"id": "12"},
+			wantErr:   "web: 缺少路径参数 name",
+		},
+		{
+			name:      "catch all",
+			routeName: "static",
+			params:    map[string]string{"filepath": "css/app.css"},
+			wantURL:   "/static/css/app.css",
+		},
+		{
+			name:      "anonymous star",
+			routeName: "any",
+			wantErr:   "web: 路由 /any/* 包含通配符 *，无法生成 URL",
+		},
+		{
+			name:      "group",
+			routeName: "login",
+			wantURL:   "/api/v1/login",
+		},
+		{
+			name:      "unknown",
+			routeName: "unknown",
+			wantErr:   "web: 找不到命名路由 unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := s.URLFor(tc.routeName, tc.params, tc.query)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantURL, u)
+		})
+	}
+}
+
+func TestHTTPServer_AddRoutes(t *testing.T) {
+	handler := func(ctx *Context) {
+		ctx.RespData = []byte("hello")
+	}
+	s := NewHTTPServer()
+	s.Get("/user/:id", handler)
+
+	err := s.AddRoutes(
+		RouteDef{Method: http.MethodGet, Path: "/order/:id", Handler: handler},
+		// 和已有的路由冲突
+		RouteDef{Method: http.MethodGet, Path: "/user/:name", Handler: handler},
+		RouteDef{Method: http.MethodGet, Path: "/user/*", Handler: handler},
+		// 非法路由
+		RouteDef{Method: http.MethodGet, Path: "order", Handler: handler},
+		RouteDef{Method: http.MethodGet, Path: "/order/:id<float>", Handler: handler},
+		// 同一批里面重复注册
+		RouteDef{Method: http.MethodPost, Path: "/order", Handler: handler},
+		RouteDef{Method: http.MethodPost, Path: "/order", Handler: handler},
+	)
+	var routeErrs RouteErrors
+	assert.True(t, errors.As(err, &routeErrs))
+	assert.Equal(t, []string{
+		"GET /user/:name: web: 路由冲突，参数路由冲突，已有 :id，新注册 :name",
+		"GET /user/*: web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [*]",
+		"GET order: web: 路由必须以 / 开头",
+		"GET /order/:id<float>: web: 非法路由，不支持的参数类型 float [:id<float>]",
+		"POST /order: web: 路由冲突[/order]",
+	}, errorStrings(routeErrs))
+	// 有错误的时候一个路由都不会注册
+	_, ok := s.findHandler(http.MethodGet, "/order/123")
+	assert.False(t, ok)
+	_, ok = s.findHandler(http.MethodPost, "/order")
+	assert.False(t, ok)
+	// 注册失败的时候也不会留下新的 HTTP 方法，否则会出现在 405 的 Allow 响应头里面
+	assert.Error(t, s.AddRoutes(RouteDef{Method: http.MethodPut, Path: "/order//1", Handler: handler}))
+	assert.Error(t, s.addRouteE(http.MethodPut, "/order//1", handler))
+	_, ok = s.trees[http.MethodPut]
+	assert.False(t, ok)
+
+	err = s.AddRoutes(
+		RouteDef{Method: http.MethodGet, Path: "/order/:id", Handler: handler},
+		RouteDef{Method: http.MethodPost, Path: "/order", Handler: handler, Middlewares: []Middleware{
+			func(next HandleFunc) HandleFunc {
+				return func(ctx *Context) {
+					next(ctx)
+					ctx.RespData = append(ctx.RespData, " world"...)
+				}
+			},
+		}},
+	)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/order", nil))
+	assert.Equal(t, "hello world", recorder.Body.String())
+}
+
+func errorStrings(errs RouteErrors) []string {
+	res := make([]string, 0, len(errs))
+	for _, err := range errs {
+		res = append(res, err.Error())
+	}
+	return res
+}
+
+// 使用 go test -bench=BenchmarkHTTPServer_ServeHTTP -run=^$ 运行
+// 关注 allocs/op，也就是每个请求的内存分配次数
+func BenchmarkHTTPServer_ServeHTTP(b *testing.B) {
+	var mdl Middleware = func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+		}
+	}
+	var handler = func(ctx *Context) {}
+
+	s := NewHTTPServer()
+	s.Use(mdl, mdl)
+	s.Get("/user/home", handler)
+	s.Get("/user/:id", handler)
+	s.Get("/order/*", handler)
+	s.UseV1(http.MethodGet, "/user/:id", mdl)
+	s.UseV1(http.MethodGet, "/order/*", mdl)
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "static",
+			path: "/user/home",
+		},
+		{
+			name: "param",
+			path: "/user/123",
+		},
+		{
+			name: "wildcard",
+			path: "/order/detail",
+		},
+	}
+	for _, tc := range testCases {
+		b.Run(tc.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			writer := &benchResponseWriter{header: http.Header{}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.ServeHTTP(writer, req)
+			}
+		})
+	}
+}
+
+// benchResponseWriter 什么也不做，避免 httptest.ResponseRecorder 的内存分配干扰结果
+type benchResponseWriter struct {
+	header http.Header
+}
+
+func (w *benchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *benchResponseWriter) Write(bs []byte) (int, error) {
+	return len(bs), nil
+}
+
+func (w *benchResponseWriter) WriteHeader(statusCode int) {}
@@ -0,0 +1,76 @@
+package cookie
+
+import (
+	"homework/homework2/session"
+	"net/http"
+)
+
+// Propagator 使用 cookie 传递 session id
+type Propagator struct {
+	cookieName string
+	// cookieOption 用于设置 cookie 的其它字段，例如 Domain、Secure 和 MaxAge
+	cookieOption func(c *http.Cookie)
+}
+
+var _ session.Propagator = &Propagator{}
+
+type PropagatorOption func(p *Propagator)
+
+func NewPropagator(opts ...PropagatorOption) *Propagator {
+	res := &Propagator{
+		cookieName:   "sessid",
+		cookieOption: func(c *http.Cookie) {},
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// WithCookieName 指定 cookie 的名字，默认是 sessid
+func WithCookieName(name string) PropagatorOption {
+	return func(p *Propagator) {
+		p.cookieName = name
+	}
+}
+
+// WithCookieOption 修改 cookie 的其它字段
+func WithCookieOption(opt func(c *http.Cookie)) PropagatorOption {
+	return func(p *Propagator) {
+		p.cookieOption = opt
+	}
+}
+
+func (p *Propagator) Inject(id string, writer http.ResponseWriter) error {
+	c := &http.Cookie{
+		Name:     p.cookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	p.cookieOption(c)
+	http.SetCookie(writer, c)
+	return nil
+}
+
+func (p *Propagator) Extract(req *http.Request) (string, error) {
+	c, err := req.Cookie(p.cookieName)
+	if err != nil {
+		return "", session.ErrSessionNotFound
+	}
+	return c.Value, nil
+}
+
+func (p *Propagator) Remove(writer http.ResponseWriter) error {
+	c := &http.Cookie{
+		Name:   p.cookieName,
+		Path:   "/",
+		MaxAge: -1,
+	}
+	p.cookieOption(c)
+	// cookieOption 可能设置了 MaxAge
+	c.MaxAge = -1
+	http.SetCookie(writer, c)
+	return nil
+}
@@ -0,0 +1,35 @@
+package cookie
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"homework/homework2/session"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPropagator(t *testing.T) {
+	p := NewPropagator(WithCookieName("my_sess"), WithCookieOption(func(c *http.Cookie) {
+		c.Secure = true
+		c.MaxAge = 3600
+	}))
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, p.Inject("sess-1", recorder))
+	assert.Equal(t, "my_sess=sess-1; Path=/; Max-Age=3600; HttpOnly; Secure; SameSite=Lax",
+		recorder.Header().Get("Set-Cookie"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := p.Extract(req)
+	assert.Equal(t, session.ErrSessionNotFound, err)
+	req.AddCookie(&http.Cookie{Name: "my_sess", Value: "sess-1"})
+	id, err := p.Extract(req)
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", id)
+
+	recorder = httptest.NewRecorder()
+	require.NoError(t, p.Remove(recorder))
+	assert.Equal(t, "my_sess=; Path=/; Max-Age=0; Secure",
+		recorder.Header().Get("Set-Cookie"))
+}
@@ -0,0 +1,41 @@
+package header
+
+import (
+	"homework/homework2/session"
+	"net/http"
+)
+
+// Propagator 使用请求头传递 session id，适合 APP 之类不方便使用 cookie 的客户端
+// 登录之后服务端在响应头里面返回 session id，之后客户端在请求头里面带上
+type Propagator struct {
+	headerName string
+}
+
+var _ session.Propagator = &Propagator{}
+
+// NewPropagator headerName 为空的时候使用 X-Session-Id
+func NewPropagator(headerName string) *Propagator {
+	if headerName == "" {
+		headerName = "X-Session-Id"
+	}
+	return &Propagator{headerName: headerName}
+}
+
+func (p *Propagator) Inject(id string, writer http.ResponseWriter) error {
+	writer.Header().Set(p.headerName, id)
+	return nil
+}
+
+func (p *Propagator) Extract(req *http.Request) (string, error) {
+	id := req.Header.Get(p.headerName)
+	if id == "" {
+		return "", session.ErrSessionNotFound
+	}
+	return id, nil
+}
+
+// Remove 返回空的 session id，客户端收到之后应该删除本地保存的 session id
+func (p *Propagator) Remove(writer http.ResponseWriter) error {
+	writer.Header().Set(p.headerName, "")
+	return nil
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	web "homework/homework2"
+)
+
+// Manager 将 Store 和 Propagator 组合在一起，方便在 HandleFunc 里面使用
+//
+//	m := &session.Manager{Store: memory.NewStore(30 * time.Minute), Propagator: cookie.NewPropagator()}
+//	sess, err := m.InitSession(ctx) // 登录成功之后
+//	sess, err = m.GetSession(ctx)   // 之后的请求
+type Manager struct {
+	Store
+	Propagator
+}
+
+// ctxKey 用于在 web.Context 里面缓存 Session
+const ctxKey = "session.session"
+
+// GetSession 获取当前请求的 Session
+// 同一个请求里面只会访问一次 Store
+func (m *Manager) GetSession(ctx *web.Context) (Session, error) {
+	if sess, ok := web.GetAs[Session](ctx, ctxKey); ok {
+		return sess, nil
+	}
+	id, err := m.Extract(ctx.Req)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := m.Get(ctx.Req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	m.cache(ctx, sess)
+	return sess, nil
+}
+
+// InitSession 创建一个新的 Session，并且写入到响应里面，一般在登录成功之后调用
+// 请求里面已经带了 Session 的话，会先删除它，避免会话固定攻击
+func (m *Manager) InitSession(ctx *web.Context) (Session, error) {
+	if oldID, err := m.Extract(ctx.Req); err == nil {
+		if err = m.Store.Remove(ctx.Req.Context(), oldID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return nil, err
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	sess, err := m.Generate(ctx.Req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Inject(id, ctx.Resp); err != nil {
+		return nil, err
+	}
+	m.cache(ctx, sess)
+	return sess, nil
+}
+
+// RefreshSession 刷新当前请求的 Session 的过期时间
+// 同时会重新写入响应，例如刷新 cookie 的过期时间
+func (m *Manager) RefreshSession(ctx *web.Context) (Session, error) {
+	sess, err := m.GetSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Refresh(ctx.Req.Context(), sess.ID()); err != nil {
+		return nil, err
+	}
+	if err = m.Inject(sess.ID(), ctx.Resp); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// RemoveSession 删除当前请求的 Session，一般在退出登录的时候调用
+func (m *Manager) RemoveSession(ctx *web.Context) error {
+	sess, err := m.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+	if err = m.Store.Remove(ctx.Req.Context(), sess.ID()); err != nil {
+		return err
+	}
+	// 清掉缓存，否则同一个请求里面的 GetSession 还能拿到已经删除的 Session
+	ctx.Delete(ctxKey)
+	return m.Propagator.Remove(ctx.Resp)
+}
+
+func (m *Manager) cache(ctx *web.Context, sess Session) {
+	ctx.Set(ctxKey, sess)
+}
+
+// newID 生成随机的 session id
+func newID() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
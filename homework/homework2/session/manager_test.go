@@ -0,0 +1,85 @@
+package session_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	web "homework/homework2"
+	"homework/homework2/session"
+	"homework/homework2/session/header"
+	"homework/homework2/session/memory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	m := &session.Manager{
+		Store:      memory.NewStore(time.Minute),
+		Propagator: header.NewPropagator(""),
+	}
+	s := web.NewHTTPServer()
+	s.Use(session.NewBuilder(m).Skip(func(ctx *web.Context) bool {
+		return ctx.Req.URL.Path == "/login"
+	}).Build())
+	s.Post("/login", func(ctx *web.Context) {
+		sess, err := m.InitSession(ctx)
+		require.NoError(t, err)
+		require.NoError(t, sess.Set(ctx.Req.Context(), "name", "Tom"))
+	})
+	s.Get("/profile", func(ctx *web.Context) {
+		sess, err := m.GetSession(ctx)
+		require.NoError(t, err)
+		name, err := sess.Get(ctx.Req.Context(), "name")
+		require.NoError(t, err)
+		ctx.RespData = []byte(name.(string))
+	})
+	s.Post("/logout", func(ctx *web.Context) {
+		require.NoError(t, m.RemoveSession(ctx))
+		_, err := m.GetSession(ctx)
+		assert.Equal(t, session.ErrSessionNotFound, err)
+	})
+
+	do := func(method string, path string, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if id != "" {
+			req.Header.Set("X-Session-Id", id)
+		}
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 没有登录
+	recorder := do(http.MethodGet, "/profile", "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, "请重新登录", recorder.Body.String())
+
+	recorder = do(http.MethodPost, "/login", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	id := recorder.Header().Get("X-Session-Id")
+	assert.Equal(t, 32, len(id))
+
+	recorder = do(http.MethodGet, "/profile", id)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "Tom", recorder.Body.String())
+	// 刷新的时候会重新写入 session id
+	assert.Equal(t, id, recorder.Header().Get("X-Session-Id"))
+
+	recorder = do(http.MethodGet, "/profile", "not-exist")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// 带着已有的 Session 重新登录，旧的 Session 会被删除
+	recorder = do(http.MethodPost, "/login", id)
+	oldID, id := id, recorder.Header().Get("X-Session-Id")
+	assert.NotEqual(t, oldID, id)
+	recorder = do(http.MethodGet, "/profile", oldID)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = do(http.MethodPost, "/logout", id)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{""}, recorder.Header().Values("X-Session-Id"))
+
+	recorder = do(http.MethodGet, "/profile", id)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"homework/homework2/session"
+	"sync"
+	"time"
+)
+
+// Store 基于内存的 session.Store，适合单机部署
+// 过期的 Session 在访问的时候删除，同时 Generate 的时候会定期清理一遍
+type Store struct {
+	// 读的时候也可能删除过期的 Session，所以用不上读锁
+	mutex      sync.Mutex
+	sessions   map[string]*Session
+	expiration time.Duration
+	// lastClean 上一次清理过期 Session 的时间
+	lastClean time.Time
+	now       func() time.Time
+}
+
+var _ session.Store = &Store{}
+
+// NewStore expiration 是 Session 的有效期，每次 Refresh 都会延长
+func NewStore(expiration time.Duration) *Store {
+	return &Store{
+		sessions:   make(map[string]*Session, 16),
+		expiration: expiration,
+		now:        time.Now,
+		lastClean:  time.Now(),
+	}
+}
+
+func (s *Store) Generate(ctx context.Context, id string) (session.Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.now()
+	if now.Sub(s.lastClean) > s.expiration {
+		s.clean(now)
+	}
+	sess := &Session{
+		id:       id,
+		values:   make(map[string]any, 4),
+		deadline: now.Add(s.expiration),
+	}
+	s.sessions[id] = sess
+	return sess, nil
+}
+
+func (s *Store) Refresh(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sess, ok := s.get(id)
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	sess.deadline = s.now().Add(s.expiration)
+	return nil
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sess, ok := s.get(id)
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+// get 查找没有过期的 Session，过期的会被删除，调用者需要持有写锁
+func (s *Store) get(id string) (*Session, bool) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if !sess.deadline.After(s.now()) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return sess, true
+}
+
+func (s *Store) clean(now time.Time) {
+	for id, sess := range s.sessions {
+		if !sess.deadline.After(now) {
+			delete(s.sessions, id)
+		}
+	}
+	s.lastClean = now
+}
+
+type Session struct {
+	id     string
+	mutex  sync.RWMutex
+	values map[string]any
+	// deadline 由 Store 维护，受 Store 的锁保护
+	deadline time.Time
+}
+
+func (s *Session) Get(ctx context.Context, key string) (any, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	val, ok := s.values[key]
+	if !ok {
+		return nil, session.ErrKeyNotFound
+	}
+	return val, nil
+}
+
+func (s *Session) Set(ctx context.Context, key string, val any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[key] = val
+	return nil
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"homework/homework2/session"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	s := NewStore(time.Minute)
+	s.now = func() time.Time {
+		return now
+	}
+	ctx := context.Background()
+
+	sess, err := s.Generate(ctx, "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", sess.ID())
+	require.NoError(t, sess.Set(ctx, "uid", 123))
+
+	sess, err = s.Get(ctx, "sess-1")
+	require.NoError(t, err)
+	val, err := sess.Get(ctx, "uid")
+	require.NoError(t, err)
+	assert.Equal(t, 123, val)
+	_, err = sess.Get(ctx, "name")
+	assert.Equal(t, session.ErrKeyNotFound, err)
+
+	_, err = s.Get(ctx, "sess-2")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+	assert.Equal(t, session.ErrSessionNotFound, s.Refresh(ctx, "sess-2"))
+
+	// 刷新之后延长有效期
+	now = now.Add(50 * time.Second)
+	require.NoError(t, s.Refresh(ctx, "sess-1"))
+	now = now.Add(50 * time.Second)
+	_, err = s.Get(ctx, "sess-1")
+	require.NoError(t, err)
+
+	// 过期了
+	now = now.Add(time.Minute)
+	_, err = s.Get(ctx, "sess-1")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+	assert.Equal(t, 0, len(s.sessions))
+
+	// 删除
+	_, err = s.Generate(ctx, "sess-3")
+	require.NoError(t, err)
+	require.NoError(t, s.Remove(ctx, "sess-3"))
+	_, err = s.Get(ctx, "sess-3")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+}
+
+func TestStore_Clean(t *testing.T) {
+	now := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	s := NewStore(time.Minute)
+	s.now = func() time.Time {
+		return now
+	}
+	s.lastClean = now
+	ctx := context.Background()
+	_, err := s.Generate(ctx, "sess-1")
+	require.NoError(t, err)
+	now = now.Add(50 * time.Second)
+	_, err = s.Generate(ctx, "sess-2")
+	require.NoError(t, err)
+
+	// 距离上一次清理超过了有效期，sess-1 被清理掉
+	now = now.Add(11 * time.Second)
+	_, err = s.Generate(ctx, "sess-3")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sess-2", "sess-3"}, keys(s.sessions))
+
+	// sess-2 过期了，但是还没到清理的时间
+	now = now.Add(50 * time.Second)
+	_, err = s.Generate(ctx, "sess-4")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sess-2", "sess-3", "sess-4"}, keys(s.sessions))
+}
+
+func keys(m map[string]*Session) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	web "homework/homework2"
+	"net/http"
+)
+
+// MiddlewareBuilder 每个请求都刷新 Session 的过期时间
+// 没有 Session 或者 Session 已经过期的请求会被拒绝，返回 401
+type MiddlewareBuilder struct {
+	manager *Manager
+	skip    func(ctx *web.Context) bool
+}
+
+func NewBuilder(manager *Manager) *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		manager: manager,
+		skip: func(ctx *web.Context) bool {
+			return false
+		},
+	}
+}
+
+// Skip 跳过不需要登录的请求，例如登录页面本身
+func (b *MiddlewareBuilder) Skip(skip func(ctx *web.Context) bool) *MiddlewareBuilder {
+	b.skip = skip
+	return b
+}
+
+func (b *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			if b.skip(ctx) {
+				next(ctx)
+				return
+			}
+			if _, err := b.manager.RefreshSession(ctx); err != nil {
+				ctx.RespStatusCode = http.StatusUnauthorized
+				ctx.RespData = []byte("请重新登录")
+				return
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrSessionNotFound session 不存在或者已经过期
+	ErrSessionNotFound = errors.New("session: 找不到 session")
+	// ErrKeyNotFound session 里面没有这个 key
+	ErrKeyNotFound = errors.New("session: 找不到 key")
+)
+
+// Session 用户的会话，用于在请求之间保存数据
+type Session interface {
+	// Get 读取数据，key 不存在的时候返回 ErrKeyNotFound
+	Get(ctx context.Context, key string) (any, error)
+	Set(ctx context.Context, key string, val any) error
+	ID() string
+}
+
+// Store 管理 Session 本身
+type Store interface {
+	// Generate 创建一个 Session
+	Generate(ctx context.Context, id string) (Session, error)
+	// Refresh 刷新 Session 的过期时间
+	Refresh(ctx context.Context, id string) error
+	Remove(ctx context.Context, id string) error
+	// Get 查找 Session，不存在或者已经过期的时候返回 ErrSessionNotFound
+	Get(ctx context.Context, id string) (Session, error)
+}
+
+// Propagator 在 HTTP 请求和响应里面传递 session id
+type Propagator interface {
+	// Inject 将 session id 写入响应
+	Inject(id string, writer http.ResponseWriter) error
+	// Extract 从请求里面读取 session id
+	Extract(req *http.Request) (string, error)
+	// Remove 告诉客户端删除 session id
+	Remove(writer http.ResponseWriter) error
+}
@@ -0,0 +1,171 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stream 流式响应，每次 Write 的数据都会立刻发送给客户端
+// 开始流式响应之后，响应头已经发送了，RespStatusCode 记录的是发送的响应码，
+// 再修改 RespStatusCode 和 RespData 都不会生效，HTTPServer 也不会再回写它们
+type Stream struct {
+	ctx     *Context
+	flusher http.Flusher
+}
+
+var errNotFlusher = errors.New("web: ResponseWriter 不支持 http.Flusher，无法流式响应")
+
+// Stream 开始流式响应，立刻发送响应头
+// 在调用之前可以通过 Resp.Header() 设置响应头
+// 如果 Resp 不支持 http.Flusher，那么返回错误
+func (c *Context) Stream(status int) (*Stream, error) {
+	if c.stream != nil {
+		return nil, errors.New("web: 已经开始流式响应了")
+	}
+	flusher, ok := c.Resp.(http.Flusher)
+	if !ok {
+		return nil, errNotFlusher
+	}
+	c.writeHeader(status)
+	c.stream.flusher = flusher
+	flusher.Flush()
+	return c.stream, nil
+}
+
+// Streaming 是否已经开始流式响应
+func (c *Context) Streaming() bool {
+	return c.stream != nil
+}
+
+// writeHeader 绕过 RespData 直接写响应的时候使用，例如静态文件里面的大文件
+// 同样记录在 RespStatusCode 上，这样 middleware 也能拿到响应码，
+// 之后 HTTPServer 不会再回写 RespStatusCode 和 RespData
+func (c *Context) writeHeader(status int) {
+	c.RespStatusCode = status
+	c.Resp.WriteHeader(status)
+	c.stream = &Stream{ctx: c}
+}
+
+// Write 发送数据，客户端断开连接之后返回 context.Canceled
+func (s *Stream) Write(p []byte) (int, error) {
+	if err := s.ctx.Req.Context().Err(); err != nil {
+		return 0, err
+	}
+	n, err := s.ctx.Resp.Write(p)
+	if err != nil {
+		return n, err
+	}
+	s.flusher.Flush()
+	return n, nil
+}
+
+// Done 客户端断开连接的时候会被关闭
+func (s *Stream) Done() <-chan struct{} {
+	return s.ctx.Req.Context().Done()
+}
+
+// SSE Server-Sent Events，参考 https://html.spec.whatwg.org/multipage/server-sent-events.html
+//
+//	sse, err := ctx.SSE()
+//	if err != nil {
+//		return
+//	}
+//	for {
+//		select {
+//		case msg := <-msgs:
+//			if err = sse.Send(web.Event{Event: "message", Data: msg}); err != nil {
+//				return
+//			}
+//		case <-sse.Done():
+//			return
+//		}
+//	}
+type SSE struct {
+	*Stream
+}
+
+// Event 一条 SSE 消息，只有 Data 是必须的
+type Event struct {
+	// ID 客户端断线重连的时候会通过 Last-Event-ID 请求头带回来
+	ID string
+	// Event 事件的名字，为空的时候客户端触发 message 事件
+	Event string
+	// Retry 告诉客户端断线之后多久重连
+	Retry time.Duration
+	// Data 可以有多行
+	Data string
+}
+
+// SSE 开始 SSE 响应
+func (c *Context) SSE() (*SSE, error) {
+	if _, ok := c.Resp.(http.Flusher); !ok {
+		return nil, errNotFlusher
+	}
+	header := c.Resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// 避免 nginx 之类的代理缓存响应
+	header.Set("X-Accel-Buffering", "no")
+	stream, err := c.Stream(http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &SSE{Stream: stream}, nil
+}
+
+// LastEventID 客户端重连的时候带上的最后一条消息的 ID
+func (c *Context) LastEventID() string {
+	return c.Req.Header.Get("Last-Event-ID")
+}
+
+// Send 发送一条消息
+func (s *SSE) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n") || strings.ContainsAny(e.Event, "\r\n") {
+		return errors.New("web: SSE 的 ID 和 Event 不能包含换行符")
+	}
+	var sb strings.Builder
+	if e.ID != "" {
+		sb.WriteString("id: ")
+		sb.WriteString(e.ID)
+		sb.WriteByte('\n')
+	}
+	if e.Event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(e.Event)
+		sb.WriteByte('\n')
+	}
+	if e.Retry > 0 {
+		sb.WriteString("retry: ")
+		sb.WriteString(strconv.FormatInt(e.Retry.Milliseconds(), 10))
+		sb.WriteByte('\n')
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	_, err := s.Write([]byte(sb.String()))
+	return err
+}
+
+// SendJSON 发送一条 JSON 格式的消息
+func (s *SSE) SendJSON(event string, val any) error {
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return s.Send(Event{Event: event, Data: string(bs)})
+}
+
+// Comment 发送注释，客户端会忽略，一般用作心跳，避免连接被代理断开
+func (s *SSE) Comment(text string) error {
+	_, err := fmt.Fprintf(s, ": %s\n\n", strings.ReplaceAll(text, "\n", " "))
+	return err
+}
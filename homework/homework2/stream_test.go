@@ -0,0 +1,114 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_SSE(t *testing.T) {
+	s := NewHTTPServer()
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			// 流式响应之后设置的这些都不会生效
+			ctx.RespStatusCode = http.StatusInternalServerError
+			ctx.RespData = []byte("error")
+		}
+	})
+	s.Get("/events", func(ctx *Context) {
+		assert.Equal(t, "3", ctx.LastEventID())
+		sse, err := ctx.SSE()
+		require.NoError(t, err)
+		assert.True(t, ctx.Streaming())
+		require.NoError(t, sse.Send(Event{ID: "4", Event: "order", Retry: 3 * time.Second, Data: "line1\nline2"}))
+		require.NoError(t, sse.Send(Event{Data: "hello"}))
+		require.NoError(t, sse.SendJSON("user", map[string]int{"id": 1}))
+		require.NoError(t, sse.Comment("ping"))
+		assert.EqualError(t, sse.Send(Event{Event: "a\nb"}), "web: SSE 的 ID 和 Event 不能包含换行符")
+		_, err = ctx.Stream(http.StatusOK)
+		assert.EqualError(t, err, "web: 已经开始流式响应了")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "3")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+	// Connection 是逐跳的请求头，交给 net/http 处理，HTTP/2 里面更是不允许出现
+	assert.Empty(t, recorder.Header().Get("Connection"))
+	assert.Equal(t, "id: 4\nevent: order\nretry: 3000\ndata: line1\ndata: line2\n\n"+
+		"data: hello\n\n"+
+		"event: user\ndata: {\"id\":1}\n\n"+
+		": ping\n\n", recorder.Body.String())
+}
+
+func TestContext_Stream(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/download", nil).WithContext(reqCtx)
+	recorder := httptest.NewRecorder()
+	ctx := &Context{Req: req, Resp: recorder}
+	stream, err := ctx.Stream(http.StatusAccepted)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, ctx.RespStatusCode)
+	_, err = stream.Write([]byte("chunk1,"))
+	require.NoError(t, err)
+
+	// 客户端断开连接
+	cancel()
+	<-stream.Done()
+	_, err = stream.Write([]byte("chunk2"))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "chunk1,", recorder.Body.String())
+
+	// 不支持 Flusher
+	ctx = &Context{Req: req, Resp: &benchResponseWriter{header: http.Header{}}}
+	_, err = ctx.SSE()
+	assert.EqualError(t, err, "web: ResponseWriter 不支持 http.Flusher，无法流式响应")
+	assert.False(t, ctx.Streaming())
+	assert.Empty(t, ctx.Resp.Header())
+}
+
+func TestContext_SSE_E2E(t *testing.T) {
+	next := make(chan struct{})
+	s := NewHTTPServer()
+	s.Get("/events", func(ctx *Context) {
+		sse, err := ctx.SSE()
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, sse.Send(Event{Data: strings.Repeat("x", i+1)}))
+			select {
+			case <-next:
+			case <-sse.Done():
+				return
+			}
+		}
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	// 在 HandleFunc 返回之前就能读到消息
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: x\n", line)
+	_, _ = reader.ReadString('\n')
+	next <- struct{}{}
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: xx\n", line)
+	next <- struct{}{}
+}
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// TemplateEngine 模板引擎
+// 使用 ServerWithTemplateEngine 设置到 HTTPServer 上，之后可以用 Context.Render 渲染页面
+type TemplateEngine interface {
+	// Render 渲染页面
+	// tplName 模板的名字，按名索引
+	// data 渲染页面用的数据
+	Render(ctx context.Context, tplName string, data any) ([]byte, error)
+}
+
+// GoTemplateEngine 基于 html/template 的模板引擎
+// 每个页面模板会单独和布局、公共片段组合成一个模板集合，
+// 所以不同页面可以用 define 定义同名的 block 而不会相互覆盖。
+//
+//	engine, err := web.NewGoTemplateEngine("./templates/pages/*.gohtml",
+//		web.TemplateWithLayout("layout", "./templates/layout.gohtml"),
+//		web.TemplateWithPartials("./templates/partials/*.gohtml"))
+//
+// 页面模板的名字是文件名，例如 user.gohtml
+type GoTemplateEngine struct {
+	// fsys 为 nil 的时候从本地文件系统读取
+	fsys fs.FS
+	// pages 页面模板的 glob
+	pages string
+	// partials 布局和公共片段的 glob，每个页面都能使用
+	partials []string
+	// layout 布局模板的名字，为空的时候直接执行页面模板
+	layout string
+	funcs  template.FuncMap
+	// reload 为 true 的时候每次渲染都重新加载模板，只应该在开发环境使用
+	reload bool
+
+	mutex sync.RWMutex
+	tpls  map[string]*template.Template
+}
+
+type GoTemplateEngineOption func(e *GoTemplateEngine)
+
+// NewGoTemplateEngine 从本地文件系统加载模板，pages 是页面模板的 glob
+func NewGoTemplateEngine(pages string, opts ...GoTemplateEngineOption) (*GoTemplateEngine, error) {
+	return NewGoTemplateEngineFS(nil, pages, opts...)
+}
+
+// NewGoTemplateEngineFS 从 fs.FS 加载模板，可以是 embed.FS
+func NewGoTemplateEngineFS(fsys fs.FS, pages string, opts ...GoTemplateEngineOption) (*GoTemplateEngine, error) {
+	res := &GoTemplateEngine{
+		fsys:  fsys,
+		pages: pages,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	// 即便是开发环境，也先加载一次，尽早暴露模板的错误
+	tpls, err := res.load()
+	if err != nil {
+		return nil, err
+	}
+	res.tpls = tpls
+	return res, nil
+}
+
+// TemplateWithLayout 使用布局模板
+// name 是布局模板的名字，也就是 define 的名字或者文件名
+// 渲染的时候执行的是布局模板，页面模板通过 define 填充布局里面的 block
+func TemplateWithLayout(name string, patterns ...string) GoTemplateEngineOption {
+	return func(e *GoTemplateEngine) {
+		e.layout = name
+		e.partials = append(e.partials, patterns...)
+	}
+}
+
+// TemplateWithPartials 公共片段，每个页面都可以用 template 引用
+func TemplateWithPartials(patterns ...string) GoTemplateEngineOption {
+	return func(e *GoTemplateEngine) {
+		e.partials = append(e.partials, patterns...)
+	}
+}
+
+// TemplateWithFuncs 注册模板函数
+func TemplateWithFuncs(funcs template.FuncMap) GoTemplateEngineOption {
+	return func(e *GoTemplateEngine) {
+		e.funcs = funcs
+	}
+}
+
+// TemplateWithReload 每次渲染都重新加载模板，修改模板之后不需要重启
+func TemplateWithReload(reload bool) GoTemplateEngineOption {
+	return func(e *GoTemplateEngine) {
+		e.reload = reload
+	}
+}
+
+func (e *GoTemplateEngine) Render(ctx context.Context, tplName string, data any) ([]byte, error) {
+	tpls := e.templates()
+	if e.reload {
+		var err error
+		if tpls, err = e.load(); err != nil {
+			return nil, err
+		}
+		e.mutex.Lock()
+		e.tpls = tpls
+		e.mutex.Unlock()
+	}
+	tpl, ok := tpls[tplName]
+	if !ok {
+		return nil, fmt.Errorf("web: 找不到模板 %s", tplName)
+	}
+	name := tplName
+	if e.layout != "" {
+		name = e.layout
+	}
+	bs := &bytes.Buffer{}
+	err := tpl.ExecuteTemplate(bs, name, data)
+	return bs.Bytes(), err
+}
+
+func (e *GoTemplateEngine) templates() map[string]*template.Template {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.tpls
+}
+
+// load 加载所有的页面模板
+func (e *GoTemplateEngine) load() (map[string]*template.Template, error) {
+	base := template.New("").Funcs(e.funcs)
+	for _, pattern := range e.partials {
+		files, err := e.glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if base, err = e.parse(base, files...); err != nil {
+			return nil, err
+		}
+	}
+	pages, err := e.glob(e.pages)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		tpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if tpl, err = e.parse(tpl, page); err != nil {
+			return nil, err
+		}
+		res[path.Base(filepath.ToSlash(page))] = tpl
+	}
+	return res, nil
+}
+
+func (e *GoTemplateEngine) glob(pattern string) ([]string, error) {
+	var files []string
+	var err error
+	if e.fsys == nil {
+		files, err = filepath.Glob(pattern)
+	} else {
+		files, err = fs.Glob(e.fsys, pattern)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("web: 没有匹配 %s 的模板", pattern)
+	}
+	return files, nil
+}
+
+func (e *GoTemplateEngine) parse(tpl *template.Template, files ...string) (*template.Template, error) {
+	if e.fsys == nil {
+		return tpl.ParseFiles(files...)
+	}
+	return tpl.ParseFS(e.fsys, files...)
+}
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGoTemplateEngine_Layout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.gohtml": {Data: []byte(`{{define "layout"}}<title>{{template "title" .}}</title>` +
+			`{{template "content" .}}{{template "footer"}}{{end}}`)},
+		"partials/footer.gohtml": {Data: []byte(`{{define "footer"}}<footer>{{year}}</footer>{{end}}`)},
+		"pages/user.gohtml": {Data: []byte(`{{define "title"}}用户{{end}}` +
+			`{{define "content"}}<p>{{.Name}}</p>{{end}}`)},
+		"pages/order.gohtml": {Data: []byte(`{{define "title"}}订单{{end}}` +
+			`{{define "content"}}<p>{{.}}</p>{{template "footer"}}{{end}}`)},
+	}
+	engine, err := NewGoTemplateEngineFS(fsys, "pages/*.gohtml",
+		TemplateWithLayout("layout", "layout.gohtml"),
+		TemplateWithPartials("partials/*.gohtml"),
+		TemplateWithFuncs(template.FuncMap{"year": func() int { return 2022 }}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		tplName string
+		data    any
+
+		wantRes string
+		wantErr string
+	}{
+		{
+			name:    "user",
+			tplName: "user.gohtml",
+			data:    map[string]string{"Name": "<Tom>"},
+			wantRes: "<title>用户</title><p>&lt;Tom&gt;</p><footer>2022</footer>",
+		},
+		{
+			// 不同的页面定义同名的 block 不会相互影响
+			name:    "order",
+			tplName: "order.gohtml",
+			data:    123,
+			wantRes: "<title>订单</title><p>123</p><footer>2022</footer><footer>2022</footer>",
+		},
+		{
+			name:    "not found",
+			tplName: "home.gohtml",
+			wantErr: "web: 找不到模板 home.gohtml",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := engine.Render(context.Background(), tc.tplName, tc.data)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, string(res))
+		})
+	}
+
+	_, err = NewGoTemplateEngineFS(fsys, "views/*.gohtml")
+	assert.EqualError(t, err, "web: 没有匹配 views/*.gohtml 的模板")
+}
+
+func TestGoTemplateEngine_Reload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hello.gohtml")
+	require.NoError(t, os.WriteFile(file, []byte(`hello, {{.}}`), 0o644))
+	pattern := filepath.Join(dir, "*.gohtml")
+
+	engine, err := NewGoTemplateEngine(pattern)
+	require.NoError(t, err)
+	devEngine, err := NewGoTemplateEngine(pattern, TemplateWithReload(true))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte(`hi, {{.}}`), 0o644))
+	res, err := engine.Render(context.Background(), "hello.gohtml", "Tom")
+	require.NoError(t, err)
+	assert.Equal(t, "hello, Tom", string(res))
+	res, err = devEngine.Render(context.Background(), "hello.gohtml", "Tom")
+	require.NoError(t, err)
+	assert.Equal(t, "hi, Tom", string(res))
+}
+
+func TestContext_Render(t *testing.T) {
+	fsys := fstest.MapFS{
+		"login.gohtml": {Data: []byte(`<form>{{.}}</form>`)},
+	}
+	engine, err := NewGoTemplateEngineFS(fsys, "*.gohtml")
+	require.NoError(t, err)
+	s := NewHTTPServer(ServerWithTemplateEngine(engine))
+	s.Get("/login", func(ctx *Context) {
+		_ = ctx.Render("login.gohtml", "login")
+	})
+	s.Get("/missing", func(ctx *Context) {
+		_ = ctx.Render("missing.gohtml", nil)
+	})
+	s.Host("admin.example.com").Get("/login", func(ctx *Context) {
+		_ = ctx.Render("login.gohtml", "admin")
+	})
+	noEngine := NewHTTPServer()
+	noEngine.Get("/login", func(ctx *Context) {
+		err := ctx.Render("login.gohtml", nil)
+		assert.EqualError(t, err, "web: 没有设置模板引擎")
+	})
+
+	testCases := []struct {
+		name   string
+		server *HTTPServer
+		url    string
+
+		wantCode int
+		wantResp string
+	}{
+		{
+			name:     "render",
+			server:   s,
+			url:      "/login",
+			wantCode: http.StatusOK,
+			wantResp: "<form>login</form>",
+		},
+		{
+			name:     "host",
+			server:   s,
+			url:      "http://admin.example.com/login",
+			wantCode: http.StatusOK,
+			wantResp: "<form>admin</form>",
+		},
+		{
+			name:     "template not found",
+			server:   s,
+			url:      "/missing",
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "no engine",
+			server:   noEngine,
+			url:      "/login",
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+			if tc.wantCode == http.StatusOK {
+				assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html"))
+			}
+		})
+	}
+}
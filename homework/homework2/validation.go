@@ -0,0 +1,393 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 校验使用 validate 标签，多个规则用逗号分隔，例如：
+//
+//	type SignupReq struct {
+//		Email    string   `json:"email" validate:"required,email"`
+//		Name     string   `json:"name" validate:"required,min=1,max=64"`
+//		Gender   string   `json:"gender" validate:"oneof=male female"`
+//		Tags     []string `json:"tags" validate:"max=8"`
+//	}
+//
+// 除了 required 以外，其它规则在字段是零值的时候都不会校验，
+// 所以可选字段只需要不写 required 就可以。
+// 嵌套的结构体、结构体切片和结构体指针会递归校验，nil 的指针除外
+const tagValidate = "validate"
+
+// FieldError 一个字段的校验错误
+type FieldError struct {
+	// Field 字段的名字，有 json 标签的时候使用 json 标签的名字
+	// 嵌套的字段形如 address.city、items[0].name
+	Field string `json:"field"`
+	// Rule 没有通过的规则
+	Rule string `json:"rule"`
+	// Param 规则的参数，例如 min=1 中的 1
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors 所有没有通过校验的字段
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return "web: 参数校验失败 " + strings.Join(msgs, "; ")
+}
+
+// RuleFunc 校验规则，val 是字段的值，指针已经被解引用了
+// param 是规则的参数，例如 min=1 中的 1，没有参数的时候是空字符串
+type RuleFunc func(val reflect.Value, param string) bool
+
+type rule struct {
+	fn RuleFunc
+	// msg 错误信息，{param} 会被替换成规则的参数
+	msg string
+	// check 解析结构体的时候检查字段的类型和规则的参数，
+	// 这样写错了的标签会在第一次校验的时候就返回错误，而不是取决于请求里面的值
+	// typ 是解引用之后的字段类型，为 nil 的时候不检查
+	check func(typ reflect.Type, param string) error
+}
+
+var (
+	rulesMutex sync.RWMutex
+	rules      = map[string]rule{
+		"required": {fn: ruleRequired, msg: "不能为空"},
+		"min":      {fn: ruleMin, msg: "不能小于 {param}", check: checkSize},
+		"max":      {fn: ruleMax, msg: "不能大于 {param}", check: checkSize},
+		"len":      {fn: ruleLen, msg: "长度必须是 {param}", check: checkSize},
+		"email":    {fn: ruleEmail, msg: "不是合法的邮箱", check: checkString},
+		"url":      {fn: ruleURL, msg: "不是合法的 URL", check: checkString},
+		"oneof":    {fn: ruleOneOf, msg: "必须是 {param} 中的一个", check: checkOneOf},
+	}
+	// fieldsCache 解析好的校验规则，key 是结构体的类型
+	fieldsCache sync.Map
+)
+
+// RegisterRule 注册校验规则，已经存在的同名规则会被覆盖
+// msg 是校验失败的错误信息，{param} 会被替换成规则的参数
+//
+//	web.RegisterRule("mobile", func(val reflect.Value, param string) bool {
+//		return mobileRegexp.MatchString(val.String())
+//	}, "不是合法的手机号")
+func RegisterRule(name string, fn RuleFunc, msg string) {
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	rules[name] = rule{fn: fn, msg: msg}
+}
+
+func getRule(name string) (rule, bool) {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+// Validate 根据 validate 标签校验结构体
+// 校验失败的时候返回 ValidationErrors，
+// val 不是结构体或者标签里面使用了不存在的规则的时候返回普通的 error
+func Validate(val any) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("web: 只支持校验结构体，实际是 %T", val)
+	}
+	var errs ValidationErrors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// BindValid 调用 Bind 之后再调用 Validate
+// 校验失败的时候，错误会被记录下来，可以使用 ValidationErrors 获取，
+// 也可以配合 validation 包里面的 middleware 直接返回 400
+func (c *Context) BindValid(val any) error {
+	if err := c.Bind(val); err != nil {
+		return err
+	}
+	err := Validate(val)
+	if errs, ok := err.(ValidationErrors); ok {
+		c.validationErrs = append(c.validationErrs, errs...)
+	}
+	return err
+}
+
+// ValidationErrors 返回 BindValid 记录的校验错误
+func (c *Context) ValidationErrors() ValidationErrors {
+	return c.validationErrs
+}
+
+// fieldRules 一个字段上的校验规则
+type fieldRules struct {
+	index int
+	name  string
+	rules []fieldRule
+}
+
+type fieldRule struct {
+	name  string
+	param string
+}
+
+func parseFields(typ reflect.Type) ([]fieldRules, error) {
+	if res, ok := fieldsCache.Load(typ); ok {
+		return res.([]fieldRules), nil
+	}
+	res := make([]fieldRules, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		fr := fieldRules{index: i, name: fieldName(field)}
+		tag := field.Tag.Get(tagValidate)
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			for _, r := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+				rl, ok := getRule(name)
+				if !ok {
+					return nil, fmt.Errorf("web: 字段 %s 使用了不存在的校验规则 %s", field.Name, name)
+				}
+				if rl.check != nil {
+					ft := field.Type
+					for ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if err := rl.check(ft, param); err != nil {
+						return nil, fmt.Errorf("web: 字段 %s 的校验规则 %s %w", field.Name, name, err)
+					}
+				}
+				fr.rules = append(fr.rules, fieldRule{name: name, param: param})
+			}
+		}
+		res = append(res, fr)
+	}
+	fieldsCache.Store(typ, res)
+	return res, nil
+}
+
+// fieldName 优先使用 json 标签的名字，这样返回给前端的错误和请求里面的字段对得上
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := parseFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		name := f.name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		// 组合的结构体，字段当作是外层结构体的
+		if rv.Type().Field(f.index).Anonymous {
+			name = prefix
+		}
+		if err = validateField(fv, name, f.rules, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateField(fv reflect.Value, name string, fieldRules []fieldRule, errs *ValidationErrors) error {
+	for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	zero := fv.IsZero()
+	for _, fr := range fieldRules {
+		if zero && fr.name != "required" {
+			continue
+		}
+		r, _ := getRule(fr.name)
+		if !r.fn(fv, fr.param) {
+			*errs = append(*errs, &FieldError{
+				Field:   name,
+				Rule:    fr.name,
+				Param:   fr.param,
+				Message: strings.ReplaceAll(r.msg, "{param}", fr.param),
+			})
+			// 一个字段只报告第一个错误
+			return nil
+		}
+	}
+	// 结构体即便是零值，也要校验里面的字段，例如 required
+	if fv.Kind() == reflect.Pointer {
+		return nil
+	}
+	switch {
+	case fv.Kind() == reflect.Struct && fv.Type() != timeType:
+		return validateStruct(fv, name, errs)
+	case fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array:
+		elemType := fv.Type().Elem()
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() != reflect.Struct || elemType == timeType {
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := validateField(fv.Index(i), fmt.Sprintf("%s[%d]", name, i), nil, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ruleRequired(val reflect.Value, param string) bool {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Map:
+		return val.Len() > 0
+	default:
+		return val.IsValid() && !val.IsZero()
+	}
+}
+
+// size 字符串是字符数，切片、map 是元素个数，数字是它本身
+// 类型已经在 checkSize 里面检查过了
+func size(val reflect.Value) float64 {
+	switch val.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(val.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(val.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint())
+	default:
+		return val.Float()
+	}
+}
+
+// parseRuleParam 参数已经在 checkSize 里面检查过了
+func parseRuleParam(typ reflect.Type, param string) (float64, error) {
+	// time.Duration 可以写成 min=1s
+	if typ == durationType {
+		if d, err := time.ParseDuration(param); err == nil {
+			return float64(d), nil
+		}
+	}
+	return strconv.ParseFloat(param, 64)
+}
+
+func checkSize(typ reflect.Type, param string) error {
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return fmt.Errorf("不支持类型 %s", typ)
+	}
+	if _, err := parseRuleParam(typ, param); err != nil {
+		return fmt.Errorf("的参数 %s 不是数字", param)
+	}
+	return nil
+}
+
+func checkString(typ reflect.Type, param string) error {
+	if typ.Kind() != reflect.String {
+		return fmt.Errorf("不支持类型 %s", typ)
+	}
+	return nil
+}
+
+func checkOneOf(typ reflect.Type, param string) error {
+	switch typ.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return fmt.Errorf("不支持类型 %s", typ)
+	}
+	if len(strings.Fields(param)) == 0 {
+		return errors.New("缺少参数")
+	}
+	return nil
+}
+
+func ruleMin(val reflect.Value, param string) bool {
+	p, _ := parseRuleParam(val.Type(), param)
+	return size(val) >= p
+}
+
+func ruleMax(val reflect.Value, param string) bool {
+	p, _ := parseRuleParam(val.Type(), param)
+	return size(val) <= p
+}
+
+func ruleLen(val reflect.Value, param string) bool {
+	p, _ := parseRuleParam(val.Type(), param)
+	return size(val) == p
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+func ruleEmail(val reflect.Value, param string) bool {
+	return val.Kind() == reflect.String && emailRegexp.MatchString(val.String())
+}
+
+func ruleURL(val reflect.Value, param string) bool {
+	if val.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(val.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func ruleOneOf(val reflect.Value, param string) bool {
+	var str string
+	switch val.Kind() {
+	case reflect.String:
+		str = val.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		str = strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		str = strconv.FormatUint(val.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if option == str {
+			return true
+		}
+	}
+	return false
+}
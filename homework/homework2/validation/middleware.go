@@ -0,0 +1,45 @@
+package validation
+
+import (
+	web "homework/homework2"
+	"net/http"
+)
+
+// MiddlewareBuilder 在 Context.BindValid 校验失败的时候返回 400，
+// 响应是 JSON，例如：
+//
+//	{"errors":[{"field":"email","rule":"email","message":"不是合法的邮箱"}]}
+//
+// 这样 HandleFunc 里面只需要在 BindValid 返回 error 的时候直接返回就可以
+type MiddlewareBuilder struct {
+	statusCode int
+}
+
+func NewBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		statusCode: http.StatusBadRequest,
+	}
+}
+
+// StatusCode 校验失败时候的响应码，例如 422
+func (b *MiddlewareBuilder) StatusCode(code int) *MiddlewareBuilder {
+	b.statusCode = code
+	return b
+}
+
+type errorResp struct {
+	Errors web.ValidationErrors `json:"errors"`
+}
+
+func (b *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			next(ctx)
+			errs := ctx.ValidationErrors()
+			if len(errs) == 0 {
+				return
+			}
+			_ = ctx.RespJSON(b.statusCode, errorResp{Errors: errs})
+		}
+	}
+}
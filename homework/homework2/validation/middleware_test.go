@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	web "homework/homework2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type signupReq struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name" validate:"required,max=8"`
+	Page  int    `query:"page" json:"-" validate:"min=1"`
+}
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	s := web.NewHTTPServer()
+	s.Use(NewBuilder().Build())
+	s.Post("/signup", func(ctx *web.Context) {
+		var req signupReq
+		if err := ctx.BindValid(&req); err != nil {
+			return
+		}
+		ctx.RespData = []byte("hello, " + req.Name)
+	})
+	s2 := web.NewHTTPServer()
+	s2.Use(NewBuilder().StatusCode(http.StatusUnprocessableEntity).Build())
+	s2.Post("/signup", func(ctx *web.Context) {
+		var req signupReq
+		_ = ctx.BindValid(&req)
+	})
+
+	testCases := []struct {
+		name   string
+		server *web.HTTPServer
+		url    string
+		body   string
+
+		wantCode int
+		wantResp string
+	}{
+		{
+			name:     "valid",
+			server:   s,
+			url:      "/signup?page=1",
+			body:     `{"email":"tom@example.com","name":"Tom"}`,
+			wantCode: http.StatusOK,
+			wantResp: "hello, Tom",
+		},
+		{
+			name:     "invalid",
+			server:   s,
+			url:      "/signup?page=-1",
+			body:     `{"email":"tom","name":""}`,
+			wantCode: http.StatusBadRequest,
+			wantResp: `{"errors":[{"field":"email","rule":"email","message":"不是合法的邮箱"},` +
+				`{"field":"name","rule":"required","message":"不能为空"},` +
+				`{"field":"Page","rule":"min","param":"1","message":"不能小于 1"}]}`,
+		},
+		{
+			name:     "status code",
+			server:   s2,
+			url:      "/signup",
+			body:     `{"email":"tom@example.com"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantResp: `{"errors":[{"field":"name","rule":"required","message":"不能为空"}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			tc.server.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantResp, recorder.Body.String())
+		})
+	}
+}
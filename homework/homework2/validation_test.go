@@ -0,0 +1,203 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type validAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=6"`
+}
+
+type validBase struct {
+	Operator string `json:"operator" validate:"required"`
+}
+
+type validUserReq struct {
+	validBase
+	Email    string          `json:"email" validate:"required,email"`
+	Name     string          `json:"name,omitempty" validate:"required,min=2,max=8"`
+	Age      int             `json:"age" validate:"min=18,max=60"`
+	Gender   string          `json:"gender" validate:"oneof=male female"`
+	Level    *int            `json:"level" validate:"required,oneof=1 2 3"`
+	Homepage string          `json:"homepage" validate:"url"`
+	Tags     []string        `json:"tags" validate:"max=2"`
+	Timeout  time.Duration   `validate:"max=3s"`
+	Address  *validAddress   `json:"address"`
+	Items    []validAddress  `json:"items"`
+	Ignored  string          `json:"ignored" validate:"-"`
+	Created  time.Time       `json:"created" validate:"required"`
+	Extra    map[string]bool `json:"extra"`
+}
+
+func TestValidate(t *testing.T) {
+	level := 2
+	badLevel := 5
+	valid := func() *validUserReq {
+		return &validUserReq{
+			validBase: validBase{Operator: "admin"},
+			Email:     "tom@example.com",
+			Name:      "汤姆",
+			Level:     &level,
+			Created:   time.Now(),
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		val      any
+		wantErrs ValidationErrors
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			val:  valid(),
+		},
+		{
+			name: "valid optional",
+			val: func() *validUserReq {
+				val := valid()
+				val.Age = 18
+				val.Gender = "female"
+				val.Homepage = "https://example.com/tom"
+				val.Tags = []string{"a", "b"}
+				val.Timeout = time.Second
+				val.Address = &validAddress{City: "深圳", Zip: "518000"}
+				return val
+			}(),
+		},
+		{
+			name: "required",
+			val:  &validUserReq{},
+			wantErrs: ValidationErrors{
+				{Field: "operator", Rule: "required", Message: "不能为空"},
+				{Field: "email", Rule: "required", Message: "不能为空"},
+				{Field: "name", Rule: "required", Message: "不能为空"},
+				{Field: "level", Rule: "required", Message: "不能为空"},
+				{Field: "created", Rule: "required", Message: "不能为空"},
+			},
+		},
+		{
+			name: "rules",
+			val: func() *validUserReq {
+				val := valid()
+				val.Email = "tom"
+				val.Name = "一二三四五六七八九"
+				val.Age = 17
+				val.Gender = "unknown"
+				val.Level = &badLevel
+				val.Homepage = "example.com"
+				val.Tags = []string{"a", "b", "c"}
+				val.Timeout = time.Minute
+				return val
+			}(),
+			wantErrs: ValidationErrors{
+				{Field: "email", Rule: "email", Message: "不是合法的邮箱"},
+				{Field: "name", Rule: "max", Param: "8", Message: "不能大于 8"},
+				{Field: "age", Rule: "min", Param: "18", Message: "不能小于 18"},
+				{Field: "gender", Rule: "oneof", Param: "male female", Message: "必须是 male female 中的一个"},
+				{Field: "level", Rule: "oneof", Param: "1 2 3", Message: "必须是 1 2 3 中的一个"},
+				{Field: "homepage", Rule: "url", Message: "不是合法的 URL"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "不能大于 2"},
+				{Field: "Timeout", Rule: "max", Param: "3s", Message: "不能大于 3s"},
+			},
+		},
+		{
+			name: "nested",
+			val: func() *validUserReq {
+				val := valid()
+				val.Address = &validAddress{Zip: "123"}
+				val.Items = []validAddress{{City: "深圳", Zip: "518000"}, {Zip: "1"}}
+				return val
+			}(),
+			wantErrs: ValidationErrors{
+				{Field: "address.city", Rule: "required", Message: "不能为空"},
+				{Field: "address.zip", Rule: "len", Param: "6", Message: "长度必须是 6"},
+				{Field: "items[1].city", Rule: "required", Message: "不能为空"},
+				{Field: "items[1].zip", Rule: "len", Param: "6", Message: "长度必须是 6"},
+			},
+		},
+		{
+			name:    "not struct",
+			val:     "abc",
+			wantErr: "web: 只支持校验结构体，实际是 string",
+		},
+		{
+			name: "unknown rule",
+			val: &struct {
+				Name string `validate:"required,unknown"`
+			}{},
+			wantErr: "web: 字段 Name 使用了不存在的校验规则 unknown",
+		},
+		{
+			// 即便字段是零值，也会返回错误
+			name: "invalid param",
+			val: &struct {
+				Age int `validate:"min=abc"`
+			}{},
+			wantErr: "web: 字段 Age 的校验规则 min 的参数 abc 不是数字",
+		},
+		{
+			name: "unsupported type",
+			val: &struct {
+				Admin *bool `validate:"max=1"`
+			}{},
+			wantErr: "web: 字段 Admin 的校验规则 max 不支持类型 bool",
+		},
+		{
+			name: "email on int",
+			val: &struct {
+				Email int `validate:"email"`
+			}{Email: 1},
+			wantErr: "web: 字段 Email 的校验规则 email 不支持类型 int",
+		},
+		{
+			name: "oneof without param",
+			val: &struct {
+				Gender string `validate:"oneof"`
+			}{},
+			wantErr: "web: 字段 Gender 的校验规则 oneof 缺少参数",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.val)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			if tc.wantErrs == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.wantErrs, err)
+		})
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	mobile := regexp.MustCompile(`^1[3-9]\d{9}$`)
+	RegisterRule("mobile", func(val reflect.Value, param string) bool {
+		return mobile.MatchString(val.String())
+	}, "不是合法的手机号")
+	RegisterRule("prefix", func(val reflect.Value, param string) bool {
+		return len(val.String()) >= len(param) && val.String()[:len(param)] == param
+	}, "必须以 {param} 开头")
+
+	type req struct {
+		Mobile string `json:"mobile" validate:"required,mobile"`
+		Code   string `json:"code" validate:"prefix=SZ"`
+	}
+	assert.NoError(t, Validate(req{Mobile: "13800138000", Code: "SZ001"}))
+	err := Validate(req{Mobile: "12345", Code: "GZ001"})
+	assert.Equal(t, ValidationErrors{
+		{Field: "mobile", Rule: "mobile", Message: "不是合法的手机号"},
+		{Field: "code", Rule: "prefix", Param: "SZ", Message: "必须以 SZ 开头"},
+	}, err)
+	assert.EqualError(t, err, "web: 参数校验失败 mobile 不是合法的手机号; code 必须以 SZ 开头")
+}
@@ -0,0 +1,552 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，也就是 RFC 6455 里面的 opcode
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// 关闭码，参考 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+// websocketGUID 用于计算 Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var errWebSocketClosed = errors.New("web: websocket 已经关闭")
+
+// CloseError 收到了对端的关闭帧，或者因为对端违反协议而关闭了连接
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("web: websocket 关闭 %d %s", e.Code, e.Text)
+}
+
+// WebSocketConn 一个 WebSocket 连接
+// 可以有一个 goroutine 读，同时有多个 goroutine 写
+type WebSocketConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	subprotocol string
+	// maxMessageSize 消息的最大长度，分片的消息按照总长度计算
+	maxMessageSize int64
+	// readLen 当前正在读的消息已经读了多少
+	readLen int64
+	// pongHandler 收到 pong 的时候调用，一般用于刷新读超时
+	pongHandler func(data string)
+
+	writeMutex sync.Mutex
+	closeSent  bool
+
+	// conns 所属的 HTTPServer 的连接集合，关闭的时候需要移除
+	conns *wsConns
+}
+
+type WebSocketOption func(u *upgrader)
+
+type upgrader struct {
+	maxMessageSize int64
+	subprotocols   []string
+	checkOrigin    func(req *http.Request) bool
+}
+
+// WebSocketWithMaxMessageSize 消息的最大长度，默认是 1MB，必须大于 0
+// 超过的时候会以 1009 关闭连接
+func WebSocketWithMaxMessageSize(size int64) WebSocketOption {
+	// 不限制的话，对端可以声明一个很大的长度，让服务端一次分配这么多内存
+	if size <= 0 {
+		panic("web: websocket 消息的最大长度必须大于 0")
+	}
+	return func(u *upgrader) {
+		u.maxMessageSize = size
+	}
+}
+
+// WebSocketWithSubprotocols 服务端支持的子协议，按照优先级排列
+// 会选择第一个客户端也支持的子协议
+func WebSocketWithSubprotocols(protocols ...string) WebSocketOption {
+	return func(u *upgrader) {
+		u.subprotocols = protocols
+	}
+}
+
+// WebSocketWithCheckOrigin 检查 Origin，默认只允许同源的请求，避免跨站 WebSocket 劫持
+func WebSocketWithCheckOrigin(check func(req *http.Request) bool) WebSocketOption {
+	return func(u *upgrader) {
+		u.checkOrigin = check
+	}
+}
+
+// Upgrade 将请求升级为 WebSocket 连接，在普通的路由里面调用即可，
+// 所以 middleware 一样会生效，例如鉴权和访问日志
+//
+//	s.Get("/ws", func(ctx *web.Context) {
+//		conn, err := ctx.Upgrade()
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		for {
+//			typ, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			_ = conn.WriteMessage(typ, msg)
+//		}
+//	})
+//
+// 握手失败的时候，会设置好 RespStatusCode 并且返回错误。
+// 升级成功之后，连接已经不归 HTTPServer 管了，RespStatusCode 和 RespData 不再生效，
+// HandleFunc 返回之前需要关闭连接。
+// 通过 App 启动的话，退出的时候还没有关闭的连接会以 1001 关闭
+func (c *Context) Upgrade(opts ...WebSocketOption) (*WebSocketConn, error) {
+	u := &upgrader{
+		maxMessageSize: 1 << 20,
+		checkOrigin:    sameOrigin,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	req := c.Req
+	if req.Method != http.MethodGet {
+		return nil, c.handshakeError(http.StatusMethodNotAllowed, "websocket 握手必须使用 GET 方法")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") ||
+		!headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return nil, c.handshakeError(http.StatusBadRequest, "不是 websocket 握手请求")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Resp.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, c.handshakeError(http.StatusUpgradeRequired, "不支持的 websocket 版本")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, c.handshakeError(http.StatusBadRequest, "非法的 Sec-WebSocket-Key")
+	}
+	if !u.checkOrigin(req) {
+		return nil, c.handshakeError(http.StatusForbidden, "不允许的 Origin")
+	}
+	hijacker, ok := c.Resp.(http.Hijacker)
+	if !ok {
+		return nil, c.handshakeError(http.StatusInternalServerError, "ResponseWriter 不支持 http.Hijacker")
+	}
+	subprotocol := selectSubprotocol(req, u.subprotocols)
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, c.handshakeError(http.StatusInternalServerError, err.Error())
+	}
+	c.hijacked = true
+	// http.Server 可能设置了超时时间，对长连接来说不合适
+	_ = conn.SetDeadline(time.Time{})
+
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	sb.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	sb.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	if _, err = brw.WriteString(sb.String()); err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	res := &WebSocketConn{
+		conn:           conn,
+		br:             brw.Reader,
+		subprotocol:    subprotocol,
+		maxMessageSize: u.maxMessageSize,
+		conns:          c.wsConns,
+	}
+	// 被接管的连接 http.Server 不会再管，需要自己记录下来，退出的时候关闭
+	if res.conns != nil {
+		res.conns.add(res)
+	}
+	return res, nil
+}
+
+func (c *Context) handshakeError(status int, msg string) error {
+	c.RespStatusCode = status
+	c.RespData = []byte(msg)
+	return errors.New("web: " + msg)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin 没有 Origin 的不是浏览器发起的请求，直接放行
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, req.Host)
+}
+
+func headerContainsToken(header http.Header, key string, token string) bool {
+	for _, val := range header.Values(key) {
+		for _, t := range strings.Split(val, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func selectSubprotocol(req *http.Request, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	offered := make(map[string]struct{}, 4)
+	for _, val := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(val, ",") {
+			offered[strings.TrimSpace(p)] = struct{}{}
+		}
+	}
+	for _, p := range supported {
+		if _, ok := offered[p]; ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// Subprotocol 协商出来的子协议
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler 收到 pong 的时候调用
+// ping 会被自动回复 pong，不需要处理
+func (c *WebSocketConn) SetPongHandler(fn func(data string)) {
+	c.pongHandler = fn
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被合并
+// 收到 ping 会自动回复 pong，收到关闭帧会回复关闭帧，然后返回 *CloseError
+func (c *WebSocketConn) ReadMessage() (int, []byte, error) {
+	var msgType int
+	var msg []byte
+	c.readLen = 0
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = c.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(string(payload))
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case continuationFrame:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "没有需要继续的消息")
+			}
+		default:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "上一条消息还没有结束")
+			}
+			msgType = opcode
+		}
+		msg = append(msg, payload...)
+		c.readLen += int64(len(payload))
+		if !fin {
+			continue
+		}
+		if msgType == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, "文本消息不是合法的 UTF-8")
+		}
+		return msgType, msg, nil
+	}
+}
+
+// ReadJSON 读取一条消息，并且按照 JSON 解析
+func (c *WebSocketConn) ReadJSON(val any) error {
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, val)
+}
+
+func (c *WebSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	// 没有协商任何扩展，所以 RSV 必须是 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "RSV 必须是 0")
+	}
+	opcode = int(header[0] & 0x0f)
+	switch opcode {
+	case continuationFrame, TextMessage, BinaryMessage, CloseMessage, PingMessage, PongMessage:
+	default:
+		return false, 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("未知的 opcode %d", opcode))
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "客户端发送的帧必须有掩码")
+	}
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		l := binary.BigEndian.Uint64(ext[:])
+		if l>>63 != 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "非法的长度")
+		}
+		length = int64(l)
+	}
+	if opcode >= CloseMessage && (!fin || length > 125) {
+		return false, 0, nil, c.fail(CloseProtocolError, "控制帧不能分片，并且不能超过 125 字节")
+	}
+	// 在读之前判断，避免对端用一个很大的长度耗尽内存
+	if opcode < CloseMessage && c.readLen+length > c.maxMessageSize {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "消息太大")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *WebSocketConn) handleClose(payload []byte) error {
+	code := CloseNoStatusReceived
+	var text string
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "非法的关闭帧")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) || !utf8.ValidString(text) {
+			return c.fail(CloseProtocolError, "非法的关闭帧")
+		}
+	}
+	// 回复关闭帧，之后由调用者关闭连接
+	var reply []byte
+	if code != CloseNoStatusReceived {
+		reply = closePayload(code, "")
+	}
+	_ = c.writeFrame(CloseMessage, reply)
+	return &CloseError{Code: code, Text: text}
+}
+
+// validCloseCode 能够出现在关闭帧里面的关闭码
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
+
+// fail 对端违反了协议，发送关闭帧之后直接关闭连接
+func (c *WebSocketConn) fail(code int, text string) error {
+	_ = c.writeFrame(CloseMessage, closePayload(code, text))
+	_ = c.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+func closePayload(code int, text string) []byte {
+	res := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(res, uint16(code))
+	copy(res[2:], text)
+	return res
+}
+
+// WriteMessage 发送一条消息，messageType 只能是 TextMessage 或者 BinaryMessage
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("web: 非法的消息类型 %d", messageType)
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteJSON 以文本消息的形式发送 JSON
+func (c *WebSocketConn) WriteJSON(val any) error {
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(TextMessage, bs)
+}
+
+// Ping 发送 ping，data 不能超过 125 字节
+func (c *WebSocketConn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("web: ping 的数据不能超过 125 字节")
+	}
+	return c.writeFrame(PingMessage, data)
+}
+
+// CloseWithCode 发送关闭帧，然后关闭连接
+func (c *WebSocketConn) CloseWithCode(code int, text string) error {
+	if len(text) > 123 {
+		return errors.New("web: 关闭原因不能超过 123 字节")
+	}
+	err := c.writeFrame(CloseMessage, closePayload(code, text))
+	if cerr := c.Close(); err == nil || err == errWebSocketClosed {
+		err = cerr
+	}
+	return err
+}
+
+// Close 直接关闭连接，不会发送关闭帧
+func (c *WebSocketConn) Close() error {
+	if c.conns != nil {
+		c.conns.remove(c)
+	}
+	return c.conn.Close()
+}
+
+// wsConns 记录升级之后还没有关闭的连接
+// http.Server 的 Shutdown 不会等待也不会关闭被接管的连接，所以需要自己关闭
+type wsConns struct {
+	mutex sync.Mutex
+	conns map[*WebSocketConn]struct{}
+}
+
+func newWSConns() *wsConns {
+	return &wsConns{conns: make(map[*WebSocketConn]struct{}, 16)}
+}
+
+func (w *wsConns) add(c *WebSocketConn) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.conns[c] = struct{}{}
+}
+
+func (w *wsConns) remove(c *WebSocketConn) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delete(w.conns, c)
+}
+
+// closeAll 以 1001 关闭所有的连接，阻塞在读上面的 goroutine 会收到错误，从而退出
+func (w *wsConns) closeAll() {
+	w.mutex.Lock()
+	conns := make([]*WebSocketConn, 0, len(w.conns))
+	for c := range w.conns {
+		conns = append(conns, c)
+	}
+	w.mutex.Unlock()
+	for _, c := range conns {
+		// 避免对端不读数据，导致发送关闭帧的时候一直阻塞
+		_ = c.SetWriteDeadline(time.Now().Add(time.Second))
+		_ = c.CloseWithCode(CloseGoingAway, "服务器关闭")
+	}
+}
+
+// writeFrame 服务端发送的帧不需要掩码
+func (c *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	// 发送了关闭帧之后，就不能再发送任何数据了
+	if c.closeSent {
+		return errWebSocketClosed
+	}
+	length := len(payload)
+	frame := make([]byte, 0, 10+length)
+	frame = append(frame, 0x80|byte(opcode))
+	switch {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,361 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 里面的例子
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestContext_Upgrade_Handshake(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		header map[string]string
+
+		wantCode int
+	}{
+		{
+			name:     "not websocket",
+			method:   http.MethodGet,
+			header:   map[string]string{"Sec-WebSocket-Version": "13"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "version",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="},
+			wantCode: http.StatusUpgradeRequired,
+		},
+		{
+			name:   "invalid key",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "abc"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "cross origin",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+				"Origin": "http://evil.com"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "post",
+			method:   http.MethodPost,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://example.com/ws", nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			ctx := &Context{Req: req, Resp: recorder}
+			conn, err := ctx.Upgrade()
+			assert.Error(t, err)
+			assert.Nil(t, conn)
+			assert.Equal(t, tc.wantCode, ctx.RespStatusCode)
+			assert.False(t, ctx.hijacked)
+		})
+	}
+}
+
+func TestContext_Upgrade(t *testing.T) {
+	var finished int32
+	serverErr := make(chan error, 1)
+	s := NewHTTPServer()
+	s.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			// 鉴权的 middleware 一样生效
+			if ctx.Req.URL.Query().Get("token") != "123" {
+				ctx.RespStatusCode = http.StatusUnauthorized
+				return
+			}
+			next(ctx)
+			atomic.AddInt32(&finished, 1)
+			// 升级之后设置的这些都不会生效
+			ctx.RespStatusCode = http.StatusInternalServerError
+		}
+	})
+	s.Get("/ws", func(ctx *Context) {
+		conn, err := ctx.Upgrade(WebSocketWithMaxMessageSize(16),
+			WebSocketWithSubprotocols("v2", "v1"))
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		pong := make(chan string, 1)
+		conn.SetPongHandler(func(data string) {
+			pong <- data
+		})
+		require.NoError(t, conn.Ping([]byte("server")))
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				serverErr <- err
+				return
+			}
+			require.NoError(t, conn.WriteMessage(typ, msg))
+			if string(msg) == "pong?" {
+				require.NoError(t, conn.WriteMessage(TextMessage, []byte(<-pong)))
+			}
+		}
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// 没有通过鉴权
+	resp, err := http.Get(server.URL + "/ws")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	t.Run("echo", func(t *testing.T) {
+		client := dialWebSocket(t, server.URL, "v1, v3")
+		defer client.conn.Close()
+		// 服务端主动 ping
+		fin, opcode, payload := client.readFrame(t)
+		assert.True(t, fin)
+		assert.Equal(t, PingMessage, opcode)
+		assert.Equal(t, "server", string(payload))
+		client.writeFrame(t, true, PongMessage, payload)
+
+		client.writeFrame(t, true, TextMessage, []byte("hello"))
+		_, opcode, payload = client.readFrame(t)
+		assert.Equal(t, TextMessage, opcode)
+		assert.Equal(t, "hello", string(payload))
+
+		// 分片的消息，中间插入了 ping
+		client.writeFrame(t, false, BinaryMessage, []byte{1, 2})
+		client.writeFrame(t, true, PingMessage, []byte("client"))
+		client.writeFrame(t, true, continuationFrame, []byte{3})
+		_, opcode, payload = client.readFrame(t)
+		assert.Equal(t, PongMessage, opcode)
+		assert.Equal(t, "client", string(payload))
+		_, opcode, payload = client.readFrame(t)
+		assert.Equal(t, BinaryMessage, opcode)
+		assert.Equal(t, []byte{1, 2, 3}, payload)
+
+		client.writeFrame(t, true, TextMessage, []byte("pong?"))
+		_, _, _ = client.readFrame(t)
+		_, _, payload = client.readFrame(t)
+		assert.Equal(t, "server", string(payload))
+
+		// 关闭握手
+		client.writeFrame(t, true, CloseMessage, closePayload(CloseGoingAway, "bye"))
+		_, opcode, payload = client.readFrame(t)
+		assert.Equal(t, CloseMessage, opcode)
+		assert.Equal(t, CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+		assert.Equal(t, &CloseError{Code: CloseGoingAway, Text: "bye"}, <-serverErr)
+		client.assertClosed(t)
+	})
+
+	testCases := []struct {
+		name   string
+		frames func(t *testing.T, client *wsTestClient)
+
+		wantCode int
+	}{
+		{
+			name: "too big",
+			frames: func(t *testing.T, client *wsTestClient) {
+				client.writeFrame(t, false, TextMessage, []byte(strings.Repeat("a", 10)))
+				client.writeFrame(t, true, continuationFrame, []byte(strings.Repeat("a", 10)))
+			},
+			wantCode: CloseMessageTooBig,
+		},
+		{
+			name: "invalid utf8",
+			frames: func(t *testing.T, client *wsTestClient) {
+				client.writeFrame(t, true, TextMessage, []byte{0xff, 0xfe})
+			},
+			wantCode: CloseInvalidFramePayloadData,
+		},
+		{
+			name: "unmasked",
+			frames: func(t *testing.T, client *wsTestClient) {
+				_, err := client.conn.Write([]byte{0x81, 0x01, 'a'})
+				require.NoError(t, err)
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "unexpected continuation",
+			frames: func(t *testing.T, client *wsTestClient) {
+				client.writeFrame(t, true, continuationFrame, []byte("a"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "fragmented control frame",
+			frames: func(t *testing.T, client *wsTestClient) {
+				client.writeFrame(t, false, PingMessage, []byte("a"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "invalid close code",
+			frames: func(t *testing.T, client *wsTestClient) {
+				client.writeFrame(t, true, CloseMessage, closePayload(1004, ""))
+			},
+			wantCode: CloseProtocolError,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := dialWebSocket(t, server.URL, "")
+			defer client.conn.Close()
+			_, _, _ = client.readFrame(t)
+			tc.frames(t, client)
+			_, opcode, payload := client.readFrame(t)
+			assert.Equal(t, CloseMessage, opcode)
+			assert.Equal(t, tc.wantCode, int(binary.BigEndian.Uint16(payload)))
+			err := <-serverErr
+			assert.Equal(t, tc.wantCode, err.(*CloseError).Code)
+			client.assertClosed(t)
+		})
+	}
+	// HandleFunc 返回之后，middleware 会继续执行
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&finished) == int32(len(testCases)+1)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketWithMaxMessageSize(t *testing.T) {
+	assert.PanicsWithValue(t, "web: websocket 消息的最大长度必须大于 0", func() {
+		WebSocketWithMaxMessageSize(0)
+	})
+}
+
+func TestApp_Stop_WebSocket(t *testing.T) {
+	s := NewHTTPServer()
+	serverErr := make(chan error, 1)
+	s.Get("/health", func(ctx *Context) {
+		ctx.RespData = []byte("ok")
+	})
+	s.Get("/ws", func(ctx *Context) {
+		conn, err := ctx.Upgrade()
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.WriteMessage(TextMessage, []byte("ready")))
+		_, _, err = conn.ReadMessage()
+		serverErr <- err
+	})
+	app := NewApp(AppWithShutdownTimeout(time.Second))
+	addr := freeAddr(t)
+	app.AddServer("ws", addr, s)
+	appErr := make(chan error, 1)
+	go func() {
+		appErr <- app.Start()
+	}()
+	assert.Equal(t, "ok", waitGet(t, "http://"+addr+"/health"))
+
+	client := dialWebSocket(t, "http://"+addr, "")
+	defer client.conn.Close()
+	_, _, payload := client.readFrame(t)
+	assert.Equal(t, "ready", string(payload))
+	app.Stop()
+
+	// 退出的时候服务端以 1001 关闭连接，HandleFunc 也会返回
+	_, opcode, payload := client.readFrame(t)
+	assert.Equal(t, CloseMessage, opcode)
+	assert.Equal(t, CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+	client.assertClosed(t)
+	assert.Error(t, <-serverErr)
+	select {
+	case err := <-appErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("App 没有退出")
+	}
+	s.wsConns.mutex.Lock()
+	assert.Empty(t, s.wsConns.conns)
+	s.wsConns.mutex.Unlock()
+}
+
+// wsTestClient 测试用的客户端，直接读写帧
+type wsTestClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, baseURL string, protocols string) *wsTestClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(baseURL, "http://"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/ws?token=123", nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", baseURL)
+	if protocols != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", protocols)
+	}
+	require.NoError(t, req.Write(conn))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+	if protocols != "" {
+		assert.Equal(t, "v1", resp.Header.Get("Sec-WebSocket-Protocol"))
+	}
+	return &wsTestClient{conn: conn, br: br}
+}
+
+func (c *wsTestClient) writeFrame(t *testing.T, fin bool, opcode int, payload []byte) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	require.NoError(t, err)
+}
+
+func (c *wsTestClient) readFrame(t *testing.T) (bool, int, []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(c.br, header[:])
+	require.NoError(t, err)
+	// 服务端发送的帧没有掩码
+	require.Zero(t, header[1]&0x80)
+	length := int(header[1] & 0x7f)
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	require.NoError(t, err)
+	return header[0]&0x80 != 0, int(header[0] & 0x0f), payload
+}
+
+func (c *wsTestClient) assertClosed(t *testing.T) {
+	_, err := c.br.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}